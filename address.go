@@ -89,11 +89,10 @@ func (p Path) String() string {
 }
 
 func ParsePath(p string) (Path, error) {
-	ss := strings.Split(p, ",")
-
-	if len(ss) == 0 {
+	if p == "" {
 		return nil, nil
 	}
+	ss := strings.Split(p, ",")
 
 	var err error
 	as := make(Path, len(ss))
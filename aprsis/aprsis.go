@@ -24,50 +24,77 @@ func (err ProtocolError) Error() string {
 	return fmt.Sprintf("aprsis: protocol error: %s", err.Line)
 }
 
+// Connect logs in to an APRS-IS server as a receive-only client.
 func Connect(proto, addr, call, filter string) (*textproto.Conn, error) {
+	return ConnectPasscode(proto, addr, call, -1, filter)
+}
+
+// ConnectPasscode logs in to an APRS-IS server with a passcode, see
+// aprs.Address.Secret. A passcode of -1 logs in receive-only.
+func ConnectPasscode(proto, addr, call string, passcode int, filter string) (*textproto.Conn, error) {
 	conn, err := textproto.Dial(proto, addr)
 	if err != nil {
 		return nil, err
 	}
+	if err = Login(conn, call, passcode, filter); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
 
+// Login logs in on a connection to an APRS-IS server, for callers that dial
+// the connection themselves. A passcode of -1 logs in receive-only.
+func Login(conn *textproto.Conn, call string, passcode int, filter string) error {
 	if filter != "" {
 		filter = " filter " + filter
 	}
 
-	if err := conn.PrintfLine("user %s pass -1 vers go-aprs %s%s", call, version, filter); err != nil {
-		return nil, err
+	if err := conn.PrintfLine("user %s pass %d vers go-aprs %s%s", call, passcode, version, filter); err != nil {
+		return err
 	}
 	for {
 		line, err := conn.ReadLine()
 		if err != nil {
-			return nil, err
+			return err
 		}
 		line = strings.ToLower(line)
 		if strings.HasPrefix(line, "# logresp ") {
-			return conn, nil
+			return nil
 		} else if strings.HasPrefix(line, "# invalid ") {
-			return nil, ProtocolError{line}
+			return ProtocolError{line}
 		} else if strings.HasPrefix(line, "# login by user not allowed") {
-			return nil, ErrNotAllowed
+			return ErrNotAllowed
 		}
 	}
 }
 
+// ReadPackets decodes packets from the connection in Lenient mode and sends
+// them to the channel. Payload decoding failures are in the packet
+// diagnostics, lines with an invalid header are logged and dropped.
 func ReadPackets(conn *textproto.Conn, packets chan aprs.Packet) error {
+	return ReadPacketsFunc(conn, packets, func(err *aprs.LineError) {
+		log.Printf("error parsing packet: %v\n", err.Err)
+	})
+}
+
+// ReadPacketsFunc is like ReadPackets, but passes lines with an invalid
+// header to lineErr, if not nil, instead of logging them.
+func ReadPacketsFunc(conn *textproto.Conn, packets chan aprs.Packet, lineErr func(*aprs.LineError)) error {
+	d := aprs.NewDecoder(conn.R)
+	d.Mode = aprs.Lenient
 	for {
-		line, err := conn.ReadLine()
+		packet, err := d.Decode()
 		if err != nil {
+			var lerr *aprs.LineError
+			if errors.As(err, &lerr) {
+				if lineErr != nil {
+					lineErr(lerr)
+				}
+				continue
+			}
 			return err
 		}
-		if len(line) == 0 || line[0] == '#' {
-			continue
-		}
-
-		packet, err := aprs.ParsePacket(line)
-		if err != nil {
-			log.Printf("error parsing packet: %v\n", err)
-			continue
-		}
 		packets <- packet
 	}
 }
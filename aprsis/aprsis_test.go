@@ -0,0 +1,74 @@
+package aprsis
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+type testConn struct {
+	io.Reader
+	io.Writer
+}
+
+func (testConn) Close() error { return nil }
+
+func TestReadPackets(t *testing.T) {
+	var (
+		input = "# aprsc 2.1.4\r\n" +
+			"N0CALL>APRS,TCPIP*:>status\r\n" +
+			"invalid\r\n" +
+			"N0CALL>APRS:!4903.50X/07201.75W-\r\n"
+		conn    = textproto.NewConn(testConn{strings.NewReader(input), io.Discard})
+		packets = make(chan aprs.Packet, 3)
+		errs    []*aprs.LineError
+	)
+	err := ReadPacketsFunc(conn, packets, func(err *aprs.LineError) {
+		errs = append(errs, err)
+	})
+	if err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	close(packets)
+
+	var raw []string
+	for p := range packets {
+		raw = append(raw, p.Raw)
+	}
+	if want := []string{"N0CALL>APRS,TCPIP*:>status", "N0CALL>APRS:!4903.50X/07201.75W-"}; strings.Join(raw, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected packets %q, got %q", want, raw)
+	}
+	if len(errs) != 1 || errs[0].Line != 3 || errs[0].Raw != "invalid" || !errors.Is(errs[0], aprs.ErrInvalidPacket) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+
+	// Without a callback, lines that fail to decode are dropped.
+	conn = textproto.NewConn(testConn{strings.NewReader(input), io.Discard})
+	packets = make(chan aprs.Packet, 3)
+	if err = ReadPacketsFunc(conn, packets, nil); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	if len(packets) != 2 {
+		t.Errorf("expected 2 packets, got %d", len(packets))
+	}
+
+	// ReadPackets logs them.
+	var b bytes.Buffer
+	log.SetOutput(&b)
+	defer log.SetOutput(os.Stderr)
+	conn = textproto.NewConn(testConn{strings.NewReader(input), io.Discard})
+	packets = make(chan aprs.Packet, 3)
+	if err = ReadPackets(conn, packets); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	if len(packets) != 2 || !strings.Contains(b.String(), "error parsing packet: aprs: header") {
+		t.Errorf("expected 2 packets and a logged error, got %d and %q", len(packets), b.String())
+	}
+}
@@ -0,0 +1,462 @@
+// Package archive stores raw APRS lines in an append-only log of gzip
+// compressed segments, and replays them.
+//
+// Every record holds the receive time, the interface the line was received
+// on, the parse status and the raw line. Segments are rotated by size and
+// age. The index file lists the time span of every closed segment, so reads
+// only open the segments that overlap the requested time span.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+var (
+	// ErrInvalidRecord signals a corrupted record.
+	ErrInvalidRecord = errors.New("archive: invalid record")
+
+	// ErrClosed signals a write to a closed writer.
+	ErrClosed = errors.New("archive: writer is closed")
+
+	// ErrOutOfOrder signals a record older than the last record written.
+	ErrOutOfOrder = errors.New("archive: record out of order")
+)
+
+const (
+	indexName     = "index"
+	segmentSuffix = ".log.gz"
+	segmentLayout = "20060102T150405.000000000Z"
+)
+
+// Record is an archived line.
+type Record struct {
+	Time      time.Time // Receive time
+	Interface string    // Interface the line was received on, such as "aprsis" or "kiss0"
+	Err       string    // Parse error, empty if the line parsed
+	Raw       string
+}
+
+func (r Record) String() string {
+	status := "ok"
+	if r.Err != "" {
+		status = strconv.Quote(r.Err)
+	}
+	return strings.Join([]string{
+		r.Time.UTC().Format(time.RFC3339Nano),
+		r.Interface,
+		status,
+		r.Raw,
+	}, "\t")
+}
+
+func parseRecord(s string) (Record, error) {
+	var (
+		r Record
+		f = strings.SplitN(s, "\t", 4)
+	)
+	if len(f) != 4 {
+		return r, ErrInvalidRecord
+	}
+	t, err := time.Parse(time.RFC3339Nano, f[0])
+	if err != nil {
+		return r, ErrInvalidRecord
+	}
+	r.Time, r.Interface, r.Raw = t, f[1], f[3]
+	if f[2] != "ok" {
+		if r.Err, err = strconv.Unquote(f[2]); err != nil {
+			return r, ErrInvalidRecord
+		}
+	}
+	return r, nil
+}
+
+// Options control segment rotation and flushing. Zero values use the
+// defaults.
+type Options struct {
+	MaxSize       int64         // Uncompressed size of a segment, default 64 MiB
+	MaxAge        time.Duration // Time span of a segment, default 1 hour
+	FlushInterval time.Duration // Maximum delay before a record is flushed, default 1 second
+}
+
+// Writer appends records to an archive.
+type Writer struct {
+	mu      sync.Mutex
+	dir     string
+	options Options
+	file    *os.File
+	gz      *gzip.Writer
+	name    string
+	start   time.Time
+	end     time.Time
+	last    time.Time
+	size    int64
+	count   int
+	closed  bool
+	timer   *time.Timer
+	err     error // Error of the last timed flush
+}
+
+// Create opens the archive in dir for writing, creating the directory if
+// needed. Records are always appended to a new segment.
+func Create(dir string, options Options) (*Writer, error) {
+	if options.MaxSize <= 0 {
+		options.MaxSize = 64 << 20
+	}
+	if options.MaxAge <= 0 {
+		options.MaxAge = time.Hour
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Writer{dir: dir, options: options}, nil
+}
+
+// WriteLine parses the raw line to determine its status and archives it.
+func (w *Writer) WriteLine(raw string, received time.Time, iface string) error {
+	r := Record{Time: received, Interface: iface, Raw: raw}
+	if _, err := aprs.ParsePacket(raw); err != nil {
+		r.Err = err.Error()
+	}
+	return w.Write(r)
+}
+
+// Write appends the record. Records must be written in time order, a record
+// older than the last one is refused with ErrOutOfOrder. Records are flushed
+// to the segment within the flush interval, so a crash loses at most the
+// records of the last interval.
+func (w *Writer) Write(r Record) error {
+	if strings.ContainsAny(r.Raw, "\r\n") || strings.ContainsAny(r.Interface, "\t\r\n") {
+		return ErrInvalidRecord
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.err; err != nil {
+		w.err = nil
+		return err
+	}
+	if r.Time.Before(w.last) {
+		return ErrOutOfOrder
+	}
+	if w.gz != nil && (w.size >= w.options.MaxSize || r.Time.Sub(w.start) >= w.options.MaxAge) {
+		if err := w.closeSegment(); err != nil {
+			return err
+		}
+	}
+	if w.gz == nil {
+		if err := w.openSegment(r.Time); err != nil {
+			return err
+		}
+	}
+
+	line := r.String() + "\n"
+	if _, err := io.WriteString(w.gz, line); err != nil {
+		return err
+	}
+	w.size += int64(len(line))
+	w.end, w.last = r.Time, r.Time
+	w.count++
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.options.FlushInterval, w.flushTimer)
+	}
+	return nil
+}
+
+// Flush writes the buffered records to the current segment.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+func (w *Writer) flush() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if w.gz == nil {
+		return nil
+	}
+	return w.gz.Flush()
+}
+
+// flushTimer flushes after the flush interval, the error is returned by the
+// next write.
+func (w *Writer) flushTimer() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.flush(); err != nil && w.err == nil {
+		w.err = err
+	}
+}
+
+// segmentName returns the file name of a segment that starts at the time, the
+// sequence number tells apart segments with the same start.
+func segmentName(start time.Time, seq int) string {
+	name := start.UTC().Format(segmentLayout)
+	if seq > 0 {
+		name += "-" + strconv.Itoa(seq)
+	}
+	return name + segmentSuffix
+}
+
+// parseSegmentName returns the start and sequence number of a segment file.
+func parseSegmentName(name string) (time.Time, int, bool) {
+	s := strings.TrimSuffix(name, segmentSuffix)
+	if s == name {
+		return time.Time{}, 0, false
+	}
+	var seq int
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		n, err := strconv.Atoi(s[i+1:])
+		if err != nil || n < 1 {
+			return time.Time{}, 0, false
+		}
+		s, seq = s[:i], n
+	}
+	start, err := time.Parse(segmentLayout, s)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return start, seq, true
+}
+
+// openSegment creates a new segment. If a segment with the same start
+// exists, such as after a rotation by size or a restart, the next sequence
+// number is used.
+func (w *Writer) openSegment(start time.Time) error {
+	var (
+		f    *os.File
+		name string
+		err  error
+	)
+	for seq := 0; ; seq++ {
+		name = segmentName(start, seq)
+		f, err = os.OpenFile(filepath.Join(w.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	w.file, w.gz, w.name = f, gzip.NewWriter(f), name
+	w.start, w.end, w.size, w.count = start, start, 0, 0
+	return nil
+}
+
+func (w *Writer) closeSegment() error {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if err := w.gz.Close(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.gz, w.file = nil, nil
+
+	f, err := os.OpenFile(filepath.Join(w.dir, indexName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	e := indexEntry{Start: w.start, End: w.end, Count: w.count, Name: w.name}
+	if _, err = fmt.Fprintln(f, e.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Close closes the current segment and adds it to the index.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.gz != nil {
+		return w.closeSegment()
+	}
+	return nil
+}
+
+// indexEntry is the time span of a closed segment.
+type indexEntry struct {
+	Start, End time.Time
+	Count      int
+	Name       string
+}
+
+func (e indexEntry) String() string {
+	return fmt.Sprintf("%s %s %d %s",
+		e.Start.UTC().Format(time.RFC3339Nano), e.End.UTC().Format(time.RFC3339Nano), e.Count, e.Name)
+}
+
+func parseIndexEntry(s string) (indexEntry, error) {
+	var (
+		e   indexEntry
+		f   = strings.Fields(s)
+		err error
+	)
+	if len(f) != 4 {
+		return e, ErrInvalidRecord
+	}
+	if e.Start, err = time.Parse(time.RFC3339Nano, f[0]); err != nil {
+		return e, err
+	}
+	if e.End, err = time.Parse(time.RFC3339Nano, f[1]); err != nil {
+		return e, err
+	}
+	if e.Count, err = strconv.Atoi(f[2]); err != nil {
+		return e, err
+	}
+	e.Name = f[3]
+	return e, nil
+}
+
+// Archive reads an archive.
+type Archive struct {
+	dir string
+}
+
+// Open opens the archive in dir for reading.
+func Open(dir string) (*Archive, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	return &Archive{dir: dir}, nil
+}
+
+// segments returns the segments that may hold records in [from, to), in
+// time order. Segments that are not indexed, because they are still being
+// written or the writer crashed, span from their start until forever.
+func (a *Archive) segments(from, to time.Time) ([]indexEntry, error) {
+	indexed := make(map[string]indexEntry)
+	if f, err := os.Open(filepath.Join(a.dir, indexName)); err == nil {
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			if e, err := parseIndexEntry(s.Text()); err == nil {
+				indexed[e.Name] = e
+			}
+		}
+		f.Close()
+		if err = s.Err(); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	names, err := filepath.Glob(filepath.Join(a.dir, "*"+segmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		segments []indexEntry
+		seqs     = make(map[string]int)
+	)
+	for _, name := range names {
+		name = filepath.Base(name)
+		start, seq, ok := parseSegmentName(name)
+		if !ok {
+			continue // not a segment
+		}
+		e, ok := indexed[name]
+		if !ok {
+			e = indexEntry{Start: start, End: time.Unix(1<<62, 0), Name: name}
+		}
+		if (to.IsZero() || e.Start.Before(to)) && (from.IsZero() || !e.End.Before(from)) {
+			segments = append(segments, e)
+			seqs[name] = seq
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		a, b := segments[i], segments[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return seqs[a.Name] < seqs[b.Name]
+	})
+	return segments, nil
+}
+
+// Records calls fn for every record received in [from, to), in time order. A
+// zero from or to leaves the span open. Iteration stops at the first error
+// returned by fn, which is returned.
+func (a *Archive) Records(from, to time.Time, fn func(Record) error) error {
+	segments, err := a.segments(from, to)
+	if err != nil {
+		return err
+	}
+	for _, e := range segments {
+		if err = a.readSegment(e.Name, from, to, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Archive) readSegment(name string, from, to time.Time, fn func(Record) error) error {
+	f, err := os.Open(filepath.Join(a.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err == io.EOF {
+		return nil // empty segment
+	} else if err != nil {
+		return fmt.Errorf("archive: %s: %v", name, err)
+	}
+	defer gz.Close()
+
+	s := bufio.NewScanner(gz)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		r, err := parseRecord(s.Text())
+		if err != nil {
+			return fmt.Errorf("archive: %s: %v", name, err)
+		}
+		if !from.IsZero() && r.Time.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !r.Time.Before(to) {
+			return nil
+		}
+		if err = fn(r); err != nil {
+			return err
+		}
+	}
+	if err = s.Err(); err != nil && err != io.ErrUnexpectedEOF {
+		// A segment that is being written, or was not closed, ends abruptly.
+		return fmt.Errorf("archive: %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+var testStart = time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)
+
+// testRecords are spaced 10 minutes apart, the sixth line does not parse.
+var testRecords = []string{
+	"N0CALL>APRS:>one",
+	"N0CALL>APRS:>two",
+	"N0CALL>APRS:>three",
+	"N0CALL>APRS:>four",
+	"N0CALL>APRS:>five",
+	"invalid",
+	"N0CALL>APRS:>seven",
+}
+
+func testArchive(t *testing.T, options Options) string {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := Create(dir, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, raw := range testRecords {
+		if err = w.WriteLine(raw, testStart.Add(time.Duration(i)*10*time.Minute), "aprsis"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func readIndex(t *testing.T, dir string) []indexEntry {
+	t.Helper()
+	f, err := os.Open(filepath.Join(dir, indexName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []indexEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		e, err := parseIndexEntry(s.Text())
+		if err != nil {
+			t.Fatalf("%q: %v", s.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestRecord(t *testing.T) {
+	for _, r := range []Record{
+		{Time: testStart, Interface: "aprsis", Raw: "N0CALL>APRS:>test"},
+		{Time: testStart.Add(time.Nanosecond), Interface: "kiss0", Err: "aprs: \"quoted\"\ttab", Raw: "a\tb"},
+		{Time: testStart},
+	} {
+		got, err := parseRecord(r.String())
+		if err != nil {
+			t.Fatalf("%q: %v", r.String(), err)
+		}
+		if !got.Time.Equal(r.Time) || got.Interface != r.Interface || got.Err != r.Err || got.Raw != r.Raw {
+			t.Errorf("expected %+v, got %+v", r, got)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"2020-03-01T09:00:00Z\taprsis\tok",
+		"yesterday\taprsis\tok\tN0CALL>APRS:>test",
+		"2020-03-01T09:00:00Z\taprsis\tfailed\tN0CALL>APRS:>test",
+	} {
+		if _, err := parseRecord(s); err != ErrInvalidRecord {
+			t.Errorf("%q: expected %v, got %v", s, ErrInvalidRecord, err)
+		}
+	}
+}
+
+func TestWriterRotate(t *testing.T) {
+	dir := testArchive(t, Options{MaxAge: 30 * time.Minute})
+
+	entries := readIndex(t, dir)
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 segments, got %+v", entries)
+	}
+	for i, want := range []struct {
+		Start, End time.Duration
+		Count      int
+	}{
+		{0, 20 * time.Minute, 3},
+		{30 * time.Minute, 50 * time.Minute, 3},
+		{60 * time.Minute, 60 * time.Minute, 1},
+	} {
+		e := entries[i]
+		if !e.Start.Equal(testStart.Add(want.Start)) || !e.End.Equal(testStart.Add(want.End)) || e.Count != want.Count {
+			t.Errorf("segment %d: expected %v-%v (%d), got %+v", i, want.Start, want.End, want.Count, e)
+		}
+		if _, err := os.Stat(filepath.Join(dir, e.Name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	dir = testArchive(t, Options{MaxSize: 1})
+	if entries = readIndex(t, dir); len(entries) != len(testRecords) {
+		t.Errorf("expected a segment per record, got %+v", entries)
+	}
+}
+
+func TestWriterErrors(t *testing.T) {
+	w, err := Create(t.TempDir(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(Record{Time: testStart, Raw: "a\nb"}); err != ErrInvalidRecord {
+		t.Errorf("expected %v, got %v", ErrInvalidRecord, err)
+	}
+	if err = w.Write(Record{Time: testStart, Interface: "a\tb"}); err != ErrInvalidRecord {
+		t.Errorf("expected %v, got %v", ErrInvalidRecord, err)
+	}
+	if err = w.Write(Record{Time: testStart}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(Record{Time: testStart}); err != nil {
+		t.Errorf("expected records with the same time to be accepted, got %v", err)
+	}
+	if err = w.Write(Record{Time: testStart.Add(-time.Second)}); err != ErrOutOfOrder {
+		t.Errorf("expected %v, got %v", ErrOutOfOrder, err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(Record{Time: testStart}); err != ErrClosed {
+		t.Errorf("expected %v, got %v", ErrClosed, err)
+	}
+	if err = w.Close(); err != nil {
+		t.Errorf("expected a second close to succeed, got %v", err)
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Create(dir, Options{FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := func() int {
+		var n int
+		if err := a.Records(time.Time{}, time.Time{}, func(Record) error { n++; return nil }); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if err = w.WriteLine(testRecords[0], testStart, "aprsis"); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); count() != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("record was not flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err = w.WriteLine(testRecords[1], testStart.Add(time.Minute), "aprsis"); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 2 {
+		t.Errorf("expected 2 records after a flush, got %d", n)
+	}
+}
+
+func TestRecords(t *testing.T) {
+	a, err := Open(testArchive(t, Options{MaxAge: 30 * time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		From, To time.Duration
+		Want     []string
+	}{
+		{-1, -1, testRecords},
+		{0, 30 * time.Minute, testRecords[:3]},
+		{25 * time.Minute, 55 * time.Minute, testRecords[3:6]},
+		{50 * time.Minute, -1, testRecords[5:]},
+		{-1, 10 * time.Minute, testRecords[:1]},
+		{2 * time.Hour, -1, nil},
+	} {
+		var from, to time.Time
+		if test.From >= 0 {
+			from = testStart.Add(test.From)
+		}
+		if test.To >= 0 {
+			to = testStart.Add(test.To)
+		}
+
+		var got []string
+		err := a.Records(from, to, func(r Record) error {
+			got = append(got, r.Raw)
+			if r.Interface != "aprsis" {
+				t.Errorf("unexpected interface %q", r.Interface)
+			}
+			if (r.Raw == "invalid") != (r.Err != "") {
+				t.Errorf("%q: unexpected error %q", r.Raw, r.Err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, "\n") != strings.Join(test.Want, "\n") {
+			t.Errorf("%v-%v: expected %q, got %q", test.From, test.To, test.Want, got)
+		}
+	}
+
+	stop := errors.New("stop")
+	var n int
+	if err = a.Records(time.Time{}, time.Time{}, func(Record) error { n++; return stop }); err != stop || n != 1 {
+		t.Errorf("expected iteration to stop with %v after 1 record, got %v after %d", stop, err, n)
+	}
+}
+
+func TestRecordsUnindexed(t *testing.T) {
+	dir := testArchive(t, Options{MaxAge: 30 * time.Minute})
+	if err := os.Remove(filepath.Join(dir, indexName)); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err = a.Records(testStart.Add(30*time.Minute), time.Time{}, func(Record) error { n++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4 records, got %d", n)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	a, err := Open(testArchive(t, Options{MaxAge: 30 * time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		packets = make(chan aprs.Packet, len(testRecords))
+		errs    []*aprs.LineError
+	)
+	err = a.Replay(time.Time{}, time.Time{}, 0, packets, func(err *aprs.LineError) {
+		errs = append(errs, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(packets)
+
+	var got []string
+	for p := range packets {
+		got = append(got, p.Raw)
+	}
+	want := append(append([]string(nil), testRecords[:5]...), testRecords[6])
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if len(errs) != 1 || errs[0].Line != 6 || errs[0].Raw != "invalid" || !errors.Is(errs[0], aprs.ErrInvalidPacket) {
+		t.Errorf("unexpected errors %v", errs)
+	}
+
+	// Without a callback, lines that fail to parse are dropped.
+	packets = make(chan aprs.Packet, len(testRecords))
+	if err = a.Replay(time.Time{}, time.Time{}, 0, packets, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(packets) != len(want) {
+		t.Errorf("expected %d packets, got %d", len(want), len(packets))
+	}
+}
+
+func TestReplaySpeed(t *testing.T) {
+	a, err := Open(testArchive(t, Options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One hour of records at 72000x takes 50ms.
+	var (
+		packets = make(chan aprs.Packet, len(testRecords))
+		start   = time.Now()
+	)
+	if err = a.Replay(time.Time{}, time.Time{}, 72000, packets, nil); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("expected replay to take at least 50ms, took %s", d)
+	}
+}
+
+func TestWriterSameStart(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Create(dir, Options{MaxSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, raw := range testRecords[:3] {
+		if err = w.WriteLine(raw, testStart, "aprsis"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart writes a record with the start time of an existing segment.
+	if w, err = Create(dir, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteLine(testRecords[3], testStart, "aprsis"); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := readIndex(t, dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	want := []string{
+		"20200301T090000.000000000Z.log.gz",
+		"20200301T090000.000000000Z-1.log.gz",
+		"20200301T090000.000000000Z-2.log.gz",
+		"20200301T090000.000000000Z-3.log.gz",
+	}
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Errorf("expected segments %q, got %q", want, names)
+	}
+
+	a, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	if err = a.Records(time.Time{}, time.Time{}, func(r Record) error {
+		got = append(got, r.Raw)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "\n") != strings.Join(testRecords[:4], "\n") {
+		t.Errorf("expected %q, got %q", testRecords[:4], got)
+	}
+}
+
+func TestSegmentName(t *testing.T) {
+	for _, seq := range []int{0, 1, 12} {
+		name := segmentName(testStart, seq)
+		start, n, ok := parseSegmentName(name)
+		if !ok || !start.Equal(testStart) || n != seq {
+			t.Errorf("%s: expected %s and %d, got %s, %d and %t", name, testStart, seq, start, n, ok)
+		}
+	}
+	for _, name := range []string{
+		"index",
+		"20200301T090000.000000000Z.log",
+		"20200301T090000.000000000Z-0.log.gz",
+		"20200301T090000.000000000Z-x.log.gz",
+		"yesterday.log.gz",
+	} {
+		if _, _, ok := parseSegmentName(name); ok {
+			t.Errorf("%s: expected no segment", name)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package archive
+
+import (
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+// Replay parses the lines received in [from, to) and sends the packets to
+// the channel, in the same way as aprsis.ReadPacketsFunc. The packets are
+// spaced as they were received, sped up by speed, a speed of 1 replays in
+// real time and a speed of 0 replays as fast as possible. Lines are parsed in
+// Lenient mode, lines with an invalid header are passed to lineErr, if not
+// nil, with Line counting the records replayed, and dropped.
+func (a *Archive) Replay(from, to time.Time, speed float64, packets chan aprs.Packet, lineErr func(*aprs.LineError)) error {
+	var (
+		first time.Time
+		start time.Time
+		line  int
+	)
+	return a.Records(from, to, func(r Record) error {
+		line++
+		if speed > 0 {
+			if first.IsZero() {
+				first, start = r.Time, time.Now()
+			}
+			offset := time.Duration(float64(r.Time.Sub(first)) / speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		packet, err := aprs.ParsePacketMode(r.Raw, aprs.Lenient)
+		if err != nil {
+			if lineErr != nil {
+				lineErr(&aprs.LineError{Line: line, Raw: r.Raw, Err: err})
+			}
+			return nil
+		}
+		packets <- packet
+		return nil
+	})
+}
@@ -23,3 +23,12 @@ func base91Decode(s string) (int, error) {
 	}
 	return n, nil
 }
+
+func base91Encode(n, width int) string {
+	var b = make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		b[i] = base91[n%91]
+		n /= 91
+	}
+	return string(b)
+}
@@ -0,0 +1,101 @@
+package aprs
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Well-known station capability tokens.
+const (
+	CapabilityIGate        = "IGATE"
+	CapabilityMessageCount = "MSG_CNT"
+	CapabilityLocalCount   = "LOC_CNT"
+)
+
+// Capabilities are the tokens of a station capabilities report, tokens
+// without a value map to an empty string.
+type Capabilities map[string]string
+
+// ParseCapabilities parses a station capabilities report (without the
+// leading '<').
+func ParseCapabilities(s string) (Capabilities, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 15, page 77 (87 in PDF)
+
+	c := make(Capabilities)
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if i := strings.IndexByte(t, '='); i >= 0 {
+			c[strings.ToUpper(t[:i])] = t[i+1:]
+		} else {
+			c[strings.ToUpper(t)] = ""
+		}
+	}
+	if len(c) == 0 {
+		return c, ErrInvalidPacket
+	}
+	return c, nil
+}
+
+// Has returns true if the capability token is present.
+func (c Capabilities) Has(token string) bool {
+	_, ok := c[token]
+	return ok
+}
+
+// Int returns the integer value of a capability token.
+func (c Capabilities) Int(token string) (int, bool) {
+	v, ok := c[token]
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+// IsIGate returns true if the station reports to be an IGate.
+func (c Capabilities) IsIGate() bool { return c.Has(CapabilityIGate) }
+
+// MessageCount is the number of messages transmitted by an IGate.
+func (c Capabilities) MessageCount() (int, bool) { return c.Int(CapabilityMessageCount) }
+
+// LocalCount is the number of local stations heard by an IGate.
+func (c Capabilities) LocalCount() (int, bool) { return c.Int(CapabilityLocalCount) }
+
+// String formats the capabilities as an APRS payload, including the leading
+// '<'. The well-known tokens come first, the remainder is sorted.
+func (c Capabilities) String() string {
+	var (
+		known = []string{CapabilityIGate, CapabilityMessageCount, CapabilityLocalCount}
+		keys  []string
+	)
+	for _, k := range known {
+		if c.Has(k) {
+			keys = append(keys, k)
+		}
+	}
+	var other []string
+	for k := range c {
+		if k != CapabilityIGate && k != CapabilityMessageCount && k != CapabilityLocalCount {
+			other = append(other, k)
+		}
+	}
+	sort.Strings(other)
+	keys = append(keys, other...)
+
+	var tokens = make([]string, len(keys))
+	for i, k := range keys {
+		if v := c[k]; v != "" {
+			tokens[i] = k + "=" + v
+		} else {
+			tokens[i] = k
+		}
+	}
+	return "<" + strings.Join(tokens, ",")
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+// config is the station configuration, in YAML or TOML:
+//
+//	callsign: N0CALL-10
+//	passcode: 12345
+//	server: rotate.aprs2.net:14580
+//	path: [WIDE1-1, WIDE2-1]
+//	symbol: /-
+//	latitude: 52.1
+//	longitude: 5.1
+//	phg: "5132"
+//	comment: Fixed station
+//	interval: 30m
+//	beacons:
+//	  - type: status
+//	    comment: On the air
+//	    interval: 1h
+//
+// Beacons inherit the station symbol, position, PHG, comment and interval
+// unless they set their own. Without beacons, a single position is sent.
+type config struct {
+	Callsign    string   `yaml:"callsign" toml:"callsign"`
+	Passcode    int      `yaml:"passcode" toml:"passcode"`
+	Server      string   `yaml:"server" toml:"server"`
+	KISS        string   `yaml:"kiss" toml:"kiss"`
+	Destination string   `yaml:"destination" toml:"destination"`
+	Path        []string `yaml:"path" toml:"path"`
+	beacon      `yaml:",inline" toml:""`
+	Beacons     []beacon `yaml:"beacons" toml:"beacons"`
+}
+
+type beacon struct {
+	Type       string   `yaml:"type" toml:"type"` // position, object, item, status or message
+	Name       string   `yaml:"name" toml:"name"` // Object or item name
+	To         string   `yaml:"to" toml:"to"`     // Message addressee
+	Symbol     string   `yaml:"symbol" toml:"symbol"`
+	Latitude   *float64 `yaml:"latitude" toml:"latitude"`
+	Longitude  *float64 `yaml:"longitude" toml:"longitude"`
+	Altitude   *float64 `yaml:"altitude" toml:"altitude"` // Meters
+	PHG        string   `yaml:"phg" toml:"phg"`
+	Comment    string   `yaml:"comment" toml:"comment"`
+	Compressed bool     `yaml:"compressed" toml:"compressed"`
+	Messaging  bool     `yaml:"messaging" toml:"messaging"`
+	Timestamp  bool     `yaml:"timestamp" toml:"timestamp"`
+	Interval   duration `yaml:"interval" toml:"interval"`
+}
+
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(b []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(b))
+	return err
+}
+
+func (d *duration) UnmarshalYAML(n *yaml.Node) error {
+	return d.UnmarshalText([]byte(n.Value))
+}
+
+func loadConfig(name string) (*config, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &config{Destination: "APRS", Passcode: -1}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".toml":
+		err = toml.Unmarshal(b, c)
+	default:
+		err = yaml.Unmarshal(b, c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	if c.Callsign == "" {
+		return nil, fmt.Errorf("%s: no callsign", name)
+	}
+	if len(c.Beacons) == 0 {
+		c.Beacons = []beacon{{}}
+	}
+	for i := range c.Beacons {
+		c.Beacons[i].inherit(c.beacon)
+	}
+	return c, nil
+}
+
+func (b *beacon) inherit(station beacon) {
+	if b.Type == "" {
+		b.Type = "position"
+	}
+	if b.Symbol == "" {
+		b.Symbol = station.Symbol
+	}
+	if b.Latitude == nil && b.Longitude == nil {
+		b.Latitude, b.Longitude = station.Latitude, station.Longitude
+	}
+	if b.Altitude == nil {
+		b.Altitude = station.Altitude
+	}
+	if b.PHG == "" {
+		b.PHG = station.PHG
+	}
+	if b.Comment == "" {
+		b.Comment = station.Comment
+	}
+	b.Compressed = b.Compressed || station.Compressed
+	b.Messaging = b.Messaging || station.Messaging
+	b.Timestamp = b.Timestamp || station.Timestamp
+	if b.Interval.Duration == 0 {
+		b.Interval = station.Interval
+	}
+}
+
+// packet builds the packet for the beacon.
+func (c *config) packet(b beacon, now time.Time) (aprs.Packet, error) {
+	var (
+		p   aprs.Packet
+		err error
+	)
+	if p.Src, err = aprs.ParseAddress(c.Callsign); err != nil {
+		return p, err
+	}
+	if p.Dst, err = aprs.ParseAddress(c.Destination); err != nil {
+		return p, err
+	}
+	if p.Path, err = aprs.ParsePath(strings.Join(c.Path, ",")); err != nil {
+		return p, err
+	}
+
+	switch b.Type {
+	case "status":
+		p.Payload = aprs.Payload(aprs.Status{Text: b.Comment}.String())
+
+	case "message":
+		if b.To == "" {
+			return p, errors.New("message without addressee")
+		}
+		p.Payload = aprs.Payload(aprs.Message{Addressee: strings.ToUpper(b.To), Text: b.Comment}.String())
+
+	case "position", "object", "item":
+		r, err := b.report(now)
+		if err != nil {
+			return p, err
+		}
+		p.Payload = aprs.Payload(r.String())
+
+	default:
+		return p, fmt.Errorf("unknown beacon type %q", b.Type)
+	}
+
+	p.Raw = p.String()
+	return p, nil
+}
+
+func (b beacon) report(now time.Time) (aprs.PositionReport, error) {
+	var r aprs.PositionReport
+	if b.Latitude == nil || b.Longitude == nil {
+		return r, fmt.Errorf("%s without position", b.Type)
+	}
+	if len(b.Symbol) != 2 {
+		return r, fmt.Errorf("invalid symbol %q", b.Symbol)
+	}
+
+	r.Position = aprs.Position{
+		Latitude:  *b.Latitude,
+		Longitude: *b.Longitude,
+		Symbol:    aprs.Symbol{b.Symbol[0], b.Symbol[1]},
+	}
+	r.Messaging = b.Messaging
+	r.Compressed = b.Compressed
+	r.Comment = b.Comment
+	if b.Altitude != nil {
+		alt := aprs.Altitude(*b.Altitude)
+		r.Altitude = &alt
+	}
+	if b.PHG != "" {
+		if len(b.PHG) != 4 {
+			return r, fmt.Errorf("invalid PHG %q", b.PHG)
+		}
+		r.PHG = &aprs.PowerHeightGain{
+			PowerCode:       b.PHG[0],
+			HeightCode:      b.PHG[1],
+			GainCode:        b.PHG[2],
+			DirectivityCode: b.PHG[3],
+		}
+	}
+
+	switch b.Type {
+	case "object", "item":
+		// Object names are 1-9 characters, item names 3-9.
+		if l := len(b.Name); l < 1 || l > 9 || (b.Type == "item" && l < 3) {
+			return r, fmt.Errorf("invalid %s name %q", b.Type, b.Name)
+		}
+		r.Object = &aprs.Object{Name: b.Name, Item: b.Type == "item"}
+		r.Time = &now
+	default:
+		if b.Timestamp {
+			r.Time = &now
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testYAML = `callsign: N0CALL-10
+passcode: 12345
+server: rotate.aprs2.net:14580
+path: [WIDE1-1, WIDE2-1]
+symbol: /-
+latitude: 52.1
+longitude: 5.1
+phg: "5132"
+comment: Fixed station
+interval: 30m
+beacons:
+  - type: position
+  - type: status
+    comment: On the air
+    interval: 1h
+  - type: object
+    name: EVENT
+    symbol: /E
+    latitude: 52.2
+    longitude: 5.2
+  - type: message
+    to: n0call-1
+    comment: Hello
+`
+
+const testTOML = `callsign = "N0CALL-10"
+passcode = 12345
+server = "rotate.aprs2.net:14580"
+path = ["WIDE1-1", "WIDE2-1"]
+symbol = "/-"
+latitude = 52.1
+longitude = 5.1
+phg = "5132"
+comment = "Fixed station"
+interval = "30m"
+
+[[beacons]]
+type = "position"
+
+[[beacons]]
+type = "status"
+comment = "On the air"
+interval = "1h"
+
+[[beacons]]
+type = "object"
+name = "EVENT"
+symbol = "/E"
+latitude = 52.2
+longitude = 5.2
+
+[[beacons]]
+type = "message"
+to = "n0call-1"
+comment = "Hello"
+`
+
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	name = filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestLoadConfig(t *testing.T) {
+	now := time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC)
+	for _, test := range []struct {
+		Name, Data string
+	}{
+		{"station.yaml", testYAML},
+		{"station.toml", testTOML},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			c, err := loadConfig(writeConfig(t, test.Name, test.Data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Callsign != "N0CALL-10" || c.Passcode != 12345 || c.Destination != "APRS" {
+				t.Errorf("unexpected station %+v", c)
+			}
+			if len(c.Beacons) != 4 {
+				t.Fatalf("expected 4 beacons, got %d", len(c.Beacons))
+			}
+
+			for i, want := range []struct {
+				Interval time.Duration
+				Raw      string
+			}{
+				{30 * time.Minute, "N0CALL-10>APRS,WIDE1-1,WIDE2-1:!5206.00N/00506.00E-PHG5132Fixed station"},
+				{time.Hour, "N0CALL-10>APRS,WIDE1-1,WIDE2-1:>On the air"},
+				{30 * time.Minute, "N0CALL-10>APRS,WIDE1-1,WIDE2-1:;EVENT    *010930z5212.00N/00512.00EEPHG5132Fixed station"},
+				{30 * time.Minute, "N0CALL-10>APRS,WIDE1-1,WIDE2-1::N0CALL-1 :Hello"},
+			} {
+				b := c.Beacons[i]
+				if b.Interval.Duration != want.Interval {
+					t.Errorf("beacon %d: expected interval %s, got %s", i, want.Interval, b.Interval)
+				}
+				p, err := c.packet(b, now)
+				if err != nil {
+					t.Errorf("beacon %d: %v", i, err)
+					continue
+				}
+				if p.Raw != want.Raw {
+					t.Errorf("beacon %d: expected %q, got %q", i, want.Raw, p.Raw)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	c, err := loadConfig(writeConfig(t, "station.yaml", "callsign: N0CALL\nsymbol: /-\nlatitude: 52.1\nlongitude: 5.1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Passcode != -1 {
+		t.Errorf("expected no passcode, got %d", c.Passcode)
+	}
+	if len(c.Beacons) != 1 || c.Beacons[0].Type != "position" {
+		t.Fatalf("expected a single position beacon, got %+v", c.Beacons)
+	}
+	p, err := c.packet(c.Beacons[0], time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "N0CALL>APRS:!5206.00N/00506.00E-"; p.Raw != want {
+		t.Errorf("expected %q, got %q", want, p.Raw)
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	for _, test := range []struct {
+		Name, Data, Err string
+	}{
+		{"station.yaml", "passcode: 12345\n", "no callsign"},
+		{"station.toml", "callsign = \n", "station.toml"},
+		{"station.yaml", "callsign: N0CALL\ninterval: soon\n", "station.yaml"},
+	} {
+		if _, err := loadConfig(writeConfig(t, test.Name, test.Data)); err == nil || !strings.Contains(err.Error(), test.Err) {
+			t.Errorf("%q: expected error containing %q, got %v", test.Data, test.Err, err)
+		}
+	}
+}
+
+func TestPacketShortName(t *testing.T) {
+	c := &config{Callsign: "N0CALL", Destination: "APRS"}
+	b := beacon{Type: "object", Name: "X", Symbol: "/-", Latitude: new(float64), Longitude: new(float64)}
+	p, err := c.packet(b, time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "N0CALL>APRS:;X        *010930z0000.00N/00000.00E-"; p.Raw != want {
+		t.Errorf("expected %q, got %q", want, p.Raw)
+	}
+}
+
+func TestPacketInvalid(t *testing.T) {
+	c := &config{Callsign: "N0CALL", Destination: "APRS"}
+	for _, b := range []beacon{
+		{Type: "position"},
+		{Type: "position", Symbol: "/", Latitude: new(float64), Longitude: new(float64)},
+		{Type: "position", Symbol: "/-", Latitude: new(float64), Longitude: new(float64), PHG: "51"},
+		{Type: "object", Symbol: "/-", Latitude: new(float64), Longitude: new(float64)},
+		{Type: "item", Name: "X", Symbol: "/-", Latitude: new(float64), Longitude: new(float64)},
+		{Type: "message"},
+		{Type: "weather"},
+	} {
+		if _, err := c.packet(b, time.Now()); err == nil {
+			t.Errorf("%+v: expected error", b)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Command aprs-beacon sends position, object, item, status and message
+// beacons to APRS-IS or to a KISS TNC.
+//
+// Usage:
+//
+//	aprs-beacon -config station.yaml [-dry-run] [-once]
+//
+// The configuration is read as TOML if the file name ends in ".toml", and as
+// YAML otherwise. Beacons are sent to the KISS TNC if "kiss" is set, either a
+// host:port of a TCP KISS server or the path of a serial device, and to the
+// APRS-IS "server" otherwise. With -dry-run, the packets are printed in TNC2
+// format instead.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+	"github.com/pd0mz/go-aprs/aprsis"
+	"github.com/pd0mz/go-aprs/kiss"
+)
+
+type sender interface {
+	send(aprs.Packet) error
+	close()
+}
+
+// dryRun prints packets in TNC2 format.
+type dryRun struct {
+	w io.Writer
+}
+
+func (d dryRun) send(p aprs.Packet) error {
+	_, err := fmt.Fprintln(d.w, p.String())
+	return err
+}
+
+func (dryRun) close() {}
+
+// aprsIS sends packets to an APRS-IS server, connecting when needed.
+type aprsIS struct {
+	server, call string
+	passcode     int
+	conn         *textproto.Conn
+}
+
+func (a *aprsIS) send(p aprs.Packet) error {
+	if a.conn == nil {
+		conn, err := aprsis.ConnectPasscode("tcp", a.server, a.call, a.passcode, "")
+		if err != nil {
+			return err
+		}
+		a.conn = conn
+	}
+	if err := a.conn.PrintfLine("%s", p.String()); err != nil {
+		a.close()
+		return err
+	}
+	return nil
+}
+
+func (a *aprsIS) close() {
+	if a.conn != nil {
+		a.conn.Close()
+		a.conn = nil
+	}
+}
+
+// tnc sends packets to a KISS TNC, opening it when needed.
+type tnc struct {
+	addr string
+	w    io.WriteCloser
+}
+
+func (t *tnc) send(p aprs.Packet) error {
+	if t.w == nil {
+		var err error
+		if strings.HasPrefix(t.addr, "/") {
+			t.w, err = os.OpenFile(t.addr, os.O_WRONLY, 0)
+		} else {
+			t.w, err = net.Dial("tcp", t.addr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := kiss.NewEncoder(t.w).Encode(p); err != nil {
+		t.close()
+		return err
+	}
+	return nil
+}
+
+func (t *tnc) close() {
+	if t.w != nil {
+		t.w.Close()
+		t.w = nil
+	}
+}
+
+func main() {
+	var (
+		name   = flag.String("config", "aprs-beacon.yaml", "station configuration file, YAML or TOML")
+		dry    = flag.Bool("dry-run", false, "print the packets instead of sending them")
+		once   = flag.Bool("once", false, "send every beacon once and exit")
+		server = flag.String("server", "", "override the APRS-IS server of the configuration")
+	)
+	flag.Parse()
+
+	c, err := loadConfig(*name)
+	if err != nil {
+		log.Fatalf("aprs-beacon: %v", err)
+	}
+	if *server != "" {
+		c.Server, c.KISS = *server, ""
+	}
+
+	var s sender
+	switch {
+	case *dry:
+		s = dryRun{w: os.Stdout}
+	case c.KISS != "":
+		s = &tnc{addr: c.KISS}
+	case c.Server != "":
+		if c.Passcode < 0 {
+			log.Fatal("aprs-beacon: a passcode is required to send to APRS-IS")
+		}
+		s = &aprsIS{server: c.Server, call: c.Callsign, passcode: c.Passcode}
+	default:
+		log.Fatal("aprs-beacon: no kiss or server configured")
+	}
+	defer s.close()
+
+	// Validate all beacons before sending any.
+	for _, b := range c.Beacons {
+		if _, err := c.packet(b, time.Now()); err != nil {
+			log.Fatalf("aprs-beacon: %s beacon: %v", b.Type, err)
+		}
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	send := func(b beacon) {
+		p, _ := c.packet(b, time.Now())
+		mu.Lock()
+		defer mu.Unlock()
+		if err := s.send(p); err != nil {
+			log.Printf("aprs-beacon: %v", err)
+		}
+	}
+
+	if *once {
+		for _, b := range c.Beacons {
+			send(b)
+		}
+		return
+	}
+
+	for _, b := range c.Beacons {
+		wg.Add(1)
+		go func(b beacon) {
+			defer wg.Done()
+			send(b)
+			if b.Interval.Duration <= 0 {
+				return
+			}
+			for range time.Tick(b.Interval.Duration) {
+				send(b)
+			}
+		}(b)
+	}
+	wg.Wait()
+}
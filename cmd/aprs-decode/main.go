@@ -0,0 +1,240 @@
+// Command aprs-decode decodes APRS packets in TNC2 format and shows what the
+// library makes of them.
+//
+// Usage:
+//
+//	aprs-decode [-format text|json|summary] [-lenient] [-i file]... [packet...]
+//
+// Packets are read from the arguments, from the input files, or from standard
+// input if neither is given. With -lenient, packets with a valid header are
+// shown with their payload decoding failures as diagnostics.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+type inputs []string
+
+func (i *inputs) String() string     { return strings.Join(*i, ",") }
+func (i *inputs) Set(s string) error { *i = append(*i, s); return nil }
+
+type decoder struct {
+	w      io.Writer
+	stdin  io.Reader
+	format string
+	mode   aprs.ParseMode
+	failed int
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run decodes the packets given by the command line arguments, and returns
+// the exit status: 0 if all packets decoded, 1 on failures and 2 on usage
+// errors.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var (
+		flags   = flag.NewFlagSet("aprs-decode", flag.ContinueOnError)
+		files   inputs
+		format  = flags.String("format", "text", "output format: text, json or summary")
+		lenient = flags.Bool("lenient", false, "report payload decoding failures as diagnostics")
+	)
+	flags.SetOutput(stderr)
+	flags.Var(&files, "i", "read packets from file (\"-\" for standard input), may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	switch *format {
+	case "text", "json", "summary":
+	default:
+		fmt.Fprintf(stderr, "aprs-decode: unknown format %q\n", *format)
+		return 2
+	}
+
+	d := &decoder{w: stdout, stdin: stdin, format: *format}
+	if *lenient {
+		d.mode = aprs.Lenient
+	}
+	for _, raw := range flags.Args() {
+		d.decode(raw)
+	}
+	for _, name := range files {
+		if err := d.decodeFile(name); err != nil {
+			fmt.Fprintf(stderr, "aprs-decode: %v\n", err)
+			return 1
+		}
+	}
+	if flags.NArg() == 0 && len(files) == 0 {
+		if err := d.decodeReader(stdin); err != nil {
+			fmt.Fprintf(stderr, "aprs-decode: %v\n", err)
+			return 1
+		}
+	}
+
+	if d.failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (d *decoder) decodeFile(name string) error {
+	if name == "-" {
+		return d.decodeReader(d.stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.decodeReader(f)
+}
+
+func (d *decoder) decodeReader(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r\n")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		d.decode(line)
+	}
+	return s.Err()
+}
+
+func (d *decoder) decode(raw string) {
+	p, err := aprs.ParsePacketMode(raw, d.mode)
+	if err != nil {
+		d.failed++
+	}
+
+	switch d.format {
+	case "json":
+		d.printJSON(raw, p, err)
+	case "summary":
+		d.printSummary(raw, p, err)
+	default:
+		d.printText(raw, p, err)
+	}
+}
+
+// errorOffset returns the offset in the raw packet where parsing failed.
+func errorOffset(err error) int {
+	var perr *aprs.ParseError
+	if errors.As(err, &perr) {
+		return perr.Offset
+	}
+	return 0
+}
+
+type jsonResult struct {
+	Raw    string       `json:"raw"`
+	Packet *aprs.Packet `json:"packet,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Offset *int         `json:"offset,omitempty"`
+}
+
+func (d *decoder) printJSON(raw string, p aprs.Packet, err error) {
+	r := jsonResult{Raw: raw}
+	if err != nil {
+		offset := errorOffset(err)
+		r.Error, r.Offset = err.Error(), &offset
+	} else {
+		r.Packet = &p
+	}
+	b, _ := json.Marshal(r)
+	fmt.Fprintln(d.w, string(b))
+}
+
+func (d *decoder) printSummary(raw string, p aprs.Packet, err error) {
+	if err != nil {
+		fmt.Fprintf(d.w, "ERROR: %v: %s\n", err, raw)
+		return
+	}
+
+	var b = []string{p.Src.String(), fmt.Sprintf("[%c]", p.Payload.Type())}
+	if p.Position != nil {
+		b = append(b, fmt.Sprintf("%.5f,%.5f", p.Position.Latitude, p.Position.Longitude))
+	}
+	if p.Symbol[0] != 0 {
+		b = append(b, p.Symbol.String())
+	}
+	switch {
+	case p.Message != nil:
+		b = append(b, fmt.Sprintf("to %s: %s", p.Message.Addressee, p.Message.Text))
+	case p.Status != nil:
+		b = append(b, p.Status.Text)
+	case p.Comment != "":
+		b = append(b, p.Comment)
+	}
+	fmt.Fprintln(d.w, strings.Join(b, " "))
+}
+
+func (d *decoder) printText(raw string, p aprs.Packet, err error) {
+	fmt.Fprintf(d.w, "%s\n", raw)
+	if err != nil {
+		offset := errorOffset(err)
+		fmt.Fprintf(d.w, "%s^\n", strings.Repeat(" ", offset))
+		fmt.Fprintf(d.w, "error: %v\n\n", err)
+		return
+	}
+
+	fmt.Fprintf(d.w, "  %-14s %s\n", "Type", p.Payload.Type())
+	printFields(d.w, "  ", reflect.ValueOf(p))
+	if dev, ok := p.Device(); ok {
+		fmt.Fprintf(d.w, "  %-14s %s %s %s\n", "Device", dev.Vendor, dev.Model, dev.Version)
+	}
+	fmt.Fprintln(d.w)
+}
+
+// printFields prints the exported, non-zero fields of a struct.
+func printFields(w io.Writer, indent string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f, fv := t.Field(i), v.Field(i)
+		if f.PkgPath != "" || f.Name == "Raw" || fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+
+		switch val := fv.Interface().(type) {
+		case aprs.Symbol:
+			fmt.Fprintf(w, "%s%-14s %s (%s)\n", indent, f.Name, string(val[:]), val)
+		case aprs.Payload:
+			fmt.Fprintf(w, "%s%-14s %s\n", indent, f.Name, string(val))
+		case []*aprs.ParseError:
+			for _, warning := range val {
+				fmt.Fprintf(w, "%s%-14s %v\n", indent, f.Name, warning)
+			}
+		case aprs.Packet:
+			fmt.Fprintf(w, "%s%-14s %s\n", indent, f.Name, val.String())
+			printFields(w, indent+"  ", fv)
+		case fmt.Stringer:
+			fmt.Fprintf(w, "%s%-14s %s\n", indent, f.Name, val)
+		default:
+			switch fv.Kind() {
+			case reflect.Struct:
+				fmt.Fprintf(w, "%s%s\n", indent, f.Name)
+				printFields(w, indent+"  ", fv)
+			case reflect.Uint8:
+				fmt.Fprintf(w, "%s%-14s %q\n", indent, f.Name, val)
+			default:
+				fmt.Fprintf(w, "%s%-14s %v\n", indent, f.Name, val)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+const (
+	testValid   = "N0CALL>APRS,WIDE1-1:!4903.50N/07201.75W-Test"
+	testInvalid = "N0CALL>APRS:!4903.50X/07201.75W-Test"
+)
+
+func TestDecoderText(t *testing.T) {
+	var b bytes.Buffer
+	d := &decoder{w: &b, format: "text"}
+	d.decode(testValid)
+	d.decode(testInvalid)
+
+	out := b.String()
+	for _, want := range []string{
+		testValid + "\n",
+		"  Src            N0CALL\n",
+		"  Symbol         /- (House QTH (VHF))\n",
+		"  Comment        Test\n",
+		testInvalid + "\n" + strings.Repeat(" ", 20) + "^\n",
+		"error: aprs: '!' latitude at offset 20: invalid position\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if d.failed != 1 {
+		t.Errorf("expected 1 failure, got %d", d.failed)
+	}
+}
+
+func TestDecoderJSON(t *testing.T) {
+	var b bytes.Buffer
+	d := &decoder{w: &b, format: "json"}
+	d.decode(testValid)
+	d.decode(testInvalid)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+
+	var ok struct {
+		Raw    string       `json:"raw"`
+		Packet *aprs.Packet `json:"packet"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &ok); err != nil {
+		t.Fatal(err)
+	}
+	if ok.Raw != testValid || ok.Packet == nil || ok.Packet.Comment != "Test" {
+		t.Errorf("unexpected result %s", lines[0])
+	}
+
+	var bad jsonResult
+	if err := json.Unmarshal([]byte(lines[1]), &bad); err != nil {
+		t.Fatal(err)
+	}
+	if bad.Raw != testInvalid || bad.Packet != nil || bad.Error == "" || bad.Offset == nil || *bad.Offset != 20 {
+		t.Errorf("unexpected result %s", lines[1])
+	}
+}
+
+func TestDecoderSummary(t *testing.T) {
+	var b bytes.Buffer
+	d := &decoder{w: &b, format: "summary"}
+	d.decode(testValid)
+	d.decode(testInvalid)
+
+	want := "N0CALL [!] 49.05833,-72.02917 House QTH (VHF) Test\n" +
+		"ERROR: aprs: '!' latitude at offset 20: invalid position: " + testInvalid + "\n"
+	if b.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, b.String())
+	}
+}
+
+func TestRun(t *testing.T) {
+	var tests = []struct {
+		Args   []string
+		Stdin  string
+		Status int
+	}{
+		{[]string{testValid}, "", 0},
+		{[]string{testValid, testInvalid}, "", 1},
+		{[]string{"-lenient", "N0CALL>APRS::PD0MZ:Hello"}, "", 0},
+		{[]string{"N0CALL>APRS::PD0MZ:Hello"}, "", 1},
+		{nil, "# comment\r\n" + testValid + "\r\n\r\n", 0},
+		{[]string{"-i", "-"}, testInvalid + "\n", 1},
+		{[]string{"-format", "xml", testValid}, "", 2},
+		{[]string{"-unknown"}, "", 2},
+		{[]string{"-i", "/nonexistent"}, "", 1},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if status := run(test.Args, strings.NewReader(test.Stdin), &stdout, &stderr); status != test.Status {
+			t.Errorf("%q: expected exit status %d, got %d\n%s%s", test.Args, test.Status, status, stdout.String(), stderr.String())
+		}
+	}
+}
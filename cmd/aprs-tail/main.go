@@ -0,0 +1,383 @@
+// Command aprs-tail streams decoded packets from an APRS-IS server.
+//
+// Usage:
+//
+//	aprs-tail -call N0CALL [-pass 12345] [-filter r/52.1/5.1/50] [flags]
+//
+// The server side filter limits what the server sends, the post-filters
+// (-types, -from, -match and -near) limit what is shown. Raw lines can be
+// logged to a file that is rotated when it grows too large.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"os/signal"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+	"github.com/pd0mz/go-aprs/aprsis"
+)
+
+// ANSI colours.
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+var typeColor = map[aprs.DataType]string{
+	'!':  colorGreen,
+	'=':  colorGreen,
+	'/':  colorGreen,
+	'@':  colorGreen,
+	'`':  colorCyan,
+	'\'': colorCyan,
+	';':  colorYellow,
+	')':  colorYellow,
+	':':  colorMagenta,
+	'>':  colorBlue,
+	'_':  colorBlue,
+	'}':  colorGray,
+}
+
+// Reconnect back-off.
+const (
+	minBackoff = time.Second
+	maxBackoff = 2 * time.Minute
+)
+
+// readTimeout is how long to wait for data before the connection is
+// considered dead, servers send a keep-alive comment about every 20 seconds.
+const readTimeout = time.Minute
+
+// backoff is the delay between reconnects, it doubles on every failed
+// attempt up to max, and is reset to min after a connection that lasted
+// longer than max.
+type backoff struct {
+	min, max time.Duration
+	delay    time.Duration
+}
+
+func (b *backoff) next(uptime time.Duration) time.Duration {
+	switch {
+	case b.delay == 0 || uptime > b.max:
+		b.delay = b.min
+	case b.delay < b.max:
+		if b.delay *= 2; b.delay > b.max {
+			b.delay = b.max
+		}
+	}
+	return b.delay
+}
+
+type filter struct {
+	types  string
+	from   []string
+	match  *regexp.Regexp
+	near   *aprs.Position
+	radius aprs.Distance
+}
+
+func (f filter) accept(line string, p aprs.Packet, err error) bool {
+	if f.match != nil && !f.match.MatchString(line) {
+		return false
+	}
+	if err != nil {
+		// Undecodable lines only pass filters on the raw line.
+		return f.types == "" && f.from == nil && f.near == nil
+	}
+	if f.types != "" && strings.IndexByte(f.types, byte(p.Payload.Type())) < 0 {
+		return false
+	}
+	if f.from != nil {
+		var ok bool
+		for _, pattern := range f.from {
+			if ok, _ = path.Match(pattern, p.Src.String()); ok {
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if f.near != nil {
+		if p.Position == nil || !p.Position.Center().WithinRadius(*f.near, f.radius) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseNear(s string) (*aprs.Position, aprs.Distance, error) {
+	part := strings.Split(s, ",")
+	if len(part) != 3 {
+		return nil, 0, fmt.Errorf("expected lat,lng,km, got %q", s)
+	}
+	var v [3]float64
+	for i, x := range part {
+		var err error
+		if v[i], err = strconv.ParseFloat(strings.TrimSpace(x), 64); err != nil {
+			return nil, 0, err
+		}
+	}
+	return &aprs.Position{Latitude: v[0], Longitude: v[1]}, aprs.Distance(v[2]) * aprs.Kilometer, nil
+}
+
+func main() {
+	var (
+		server  = flag.String("server", "rotate.aprs2.net:14580", "APRS-IS server address")
+		call    = flag.String("call", "", "callsign to log in with (required)")
+		pass    = flag.Int("pass", -1, "passcode, -1 for a receive-only login")
+		filters = flag.String("filter", "", "server side filter, such as r/52.1/5.1/50")
+		types   = flag.String("types", "", "only show these data type identifiers, such as \"!=/@\"")
+		from    = flag.String("from", "", "only show packets from these comma separated sources, * wildcards allowed")
+		match   = flag.String("match", "", "only show raw lines matching the regular expression")
+		near    = flag.String("near", "", "only show positions within lat,lng,km")
+		color   = flag.Bool("color", os.Getenv("NO_COLOR") == "", "colour output per data type")
+		logName = flag.String("log", "", "log raw lines to file")
+		logSize = flag.Int64("log-size", 10, "rotate the log file at this size in MiB")
+		logKeep = flag.Int("log-keep", 5, "number of rotated log files to keep")
+	)
+	flag.Parse()
+
+	if *call == "" {
+		fmt.Fprintln(os.Stderr, "aprs-tail: -call is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		f   = filter{types: *types}
+		err error
+	)
+	if *from != "" {
+		f.from = strings.Split(strings.ToUpper(*from), ",")
+	}
+	if *match != "" {
+		if f.match, err = regexp.Compile(*match); err != nil {
+			log.Fatalf("aprs-tail: -match: %v", err)
+		}
+	}
+	if *near != "" {
+		if f.near, f.radius, err = parseNear(*near); err != nil {
+			log.Fatalf("aprs-tail: -near: %v", err)
+		}
+	}
+
+	var (
+		raw     io.Writer = io.Discard
+		rotated *rotatingFile
+	)
+	if *logName != "" {
+		if rotated, err = openRotatingFile(*logName, *logSize<<20, *logKeep); err != nil {
+			log.Fatalf("aprs-tail: %v", err)
+		}
+		raw = rotated
+	}
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	t := tail{out: os.Stdout, raw: raw, filter: f, color: *color}
+	t.follow(func() (conn, error) {
+		c, err := dialServer(*server, *call, *pass, *filters, readTimeout)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("connected to %s", *server)
+		return c, nil
+	}, backoff{min: minBackoff, max: maxBackoff}, stop)
+
+	if rotated != nil {
+		if err = rotated.Close(); err != nil {
+			log.Fatalf("aprs-tail: %v", err)
+		}
+	}
+}
+
+// conn is a connection to an APRS-IS server.
+type conn interface {
+	io.Reader
+	Close() error
+}
+
+// deadlineConn sets a deadline before every read, so a connection that
+// went silent, such as a half-open TCP connection, fails.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c deadlineConn) Read(b []byte) (int, error) {
+	if err := c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// serverConn reads the lines following the login.
+type serverConn struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// dialServer connects and logs in to an APRS-IS server, reads time out after
+// the timeout.
+func dialServer(addr, call string, pass int, filter string, timeout time.Duration) (conn, error) {
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	tc := textproto.NewConn(deadlineConn{Conn: nc, timeout: timeout})
+	if err = aprsis.Login(tc, call, pass, filter); err != nil {
+		tc.Close()
+		return nil, err
+	}
+	return serverConn{Reader: tc.R, Closer: tc}, nil
+}
+
+type tail struct {
+	out    io.Writer
+	raw    io.Writer
+	filter filter
+	color  bool
+}
+
+// follow shows the lines read from connections made by dial, and reconnects
+// with back-off when a connection fails, until stop is closed. Every
+// connection is closed before the next one is made.
+func (t tail) follow(dial func() (conn, error), b backoff, stop <-chan struct{}) {
+	for {
+		start := time.Now()
+		c, err := dial()
+		if err == nil {
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-stop:
+					c.Close() // unblock run
+				case <-done:
+				}
+			}()
+			err = t.run(c)
+			close(done)
+			c.Close()
+		}
+
+		delay := b.next(time.Since(start))
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		log.Printf("%v, reconnecting in %s", err, delay)
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// run shows the packets read from r. Server comments and keep-alives are
+// skipped, packets are decoded in Lenient mode.
+func (t tail) run(r io.Reader) error {
+	d := aprs.NewDecoder(r)
+	d.Mode = aprs.Lenient
+	for {
+		p, err := d.Decode()
+		var lerr *aprs.LineError
+		if errors.As(err, &lerr) {
+			p.Raw, err = lerr.Raw, lerr.Err
+		} else if err != nil {
+			return err
+		}
+		if _, werr := fmt.Fprintf(t.raw, "%s %s\n", time.Now().UTC().Format(time.RFC3339), p.Raw); werr != nil {
+			log.Printf("aprs-tail: log: %v", werr)
+		}
+
+		if !t.filter.accept(p.Raw, p, err) {
+			continue
+		}
+		t.print(p.Raw, p, err)
+	}
+}
+
+// fatal returns the first fatal diagnostic of a packet decoded in Lenient
+// mode, if any.
+func fatal(p aprs.Packet) error {
+	for _, d := range p.Diagnostics {
+		if d.Fatal() {
+			return d
+		}
+	}
+	return nil
+}
+
+func (t tail) print(line string, p aprs.Packet, err error) {
+	var (
+		now = time.Now().Format("15:04:05")
+		c   string
+		s   string
+	)
+	switch {
+	case err != nil:
+		c, s = colorRed, fmt.Sprintf("%s (%v)", line, err)
+	case fatal(p) != nil:
+		c, s = colorRed, fmt.Sprintf("%s (%v)", line, fatal(p))
+	default:
+		c, s = typeColor[p.Payload.Type()], summary(p)
+	}
+	if t.color && c != "" {
+		fmt.Fprintf(t.out, "%s %s%s%s\n", now, c, s, colorReset)
+	} else {
+		fmt.Fprintf(t.out, "%s %s\n", now, s)
+	}
+}
+
+func summary(p aprs.Packet) string {
+	var b = []string{fmt.Sprintf("%-9s", p.Src), fmt.Sprintf("[%c]", p.Payload.Type())}
+	if p.Object != nil {
+		b = append(b, p.Object.Name)
+	}
+	if p.Position != nil {
+		b = append(b, fmt.Sprintf("%.5f,%.5f", p.Position.Latitude, p.Position.Longitude))
+	}
+	if p.Symbol[0] != 0 {
+		b = append(b, p.Symbol.String())
+	}
+	switch {
+	case p.Message != nil:
+		b = append(b, fmt.Sprintf("to %s: %s", p.Message.Addressee, p.Message.Text))
+	case p.Status != nil:
+		b = append(b, p.Status.Text)
+	case p.ThirdParty != nil:
+		b = append(b, "via", summary(*p.ThirdParty))
+	case p.Comment != "":
+		b = append(b, strings.TrimSpace(p.Comment))
+	}
+	return strings.Join(b, " ")
+}
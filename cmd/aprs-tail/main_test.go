@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "raw.log")
+	r, err := openRotatingFile(name, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err = fmt.Fprintf(r, "line %d\n", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every line exceeds the size of the previous file, the oldest is dropped.
+	for suffix, want := range map[string]string{
+		"":   "line 3\n",
+		".1": "line 2\n",
+		".2": "line 1\n",
+	} {
+		b, err := os.ReadFile(name + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: expected %q, got %q", suffix, want, b)
+		}
+	}
+	if _, err = os.Stat(name + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected no third rotated file, got %v", err)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "raw.log")
+	r, err := openRotatingFile(name, 20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err = io.WriteString(r, "first line\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in the way of the rotated file fails the rotation.
+	if err = os.Mkdir(name+".1", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(name+".1", "x"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.WriteString(r, "second line\n"); err == nil {
+		t.Fatal("expected rotation error")
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "first line\nsecond line\n" {
+		t.Errorf("expected the line to be written after a failed rotation, got %q", b)
+	}
+
+	// The rotation is retried after another 20 bytes.
+	if _, err = io.WriteString(r, "third\n"); err != nil {
+		t.Errorf("expected no rotation before the size is reached, got %v", err)
+	}
+	if err = os.RemoveAll(name + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = io.WriteString(r, "fourth\n"); err != nil {
+		t.Fatal(err)
+	}
+	for suffix, want := range map[string]string{
+		"":   "fourth\n",
+		".1": "first line\nsecond line\nthird\n",
+	} {
+		b, err := os.ReadFile(name + suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("%s: expected %q, got %q", suffix, want, b)
+		}
+	}
+}
+
+func TestTailRawError(t *testing.T) {
+	var (
+		out bytes.Buffer
+		buf bytes.Buffer
+	)
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tl := tail{out: &out, raw: errWriter{}}
+	if err := tl.run(newTestConn("N0CALL>APRS:>test")); err != io.EOF {
+		t.Fatalf("expected %v, got %v", io.EOF, err)
+	}
+	if !strings.Contains(buf.String(), "log: disk full") {
+		t.Errorf("expected the log error to be reported, got %q", buf.String())
+	}
+	if !strings.Contains(out.String(), "[>] test") {
+		t.Errorf("expected the packet to be shown, got %q", out.String())
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestBackoff(t *testing.T) {
+	b := backoff{min: time.Second, max: 5 * time.Second}
+	var got []time.Duration
+	for _, uptime := range []time.Duration{0, 0, 0, 0, 0, time.Minute, 0} {
+		got = append(got, b.next(uptime))
+	}
+	want := []time.Duration{1, 2, 4, 5, 5, 1, 2}
+	for i := range want {
+		if got[i] != want[i]*time.Second {
+			t.Fatalf("expected delays %v seconds, got %v", want, got)
+		}
+	}
+}
+
+type testConn struct {
+	mu     sync.Mutex
+	r      io.Reader
+	closed bool
+}
+
+func newTestConn(lines ...string) *testConn {
+	return &testConn{r: strings.NewReader(strings.Join(lines, "\r\n") + "\r\n")}
+}
+
+func (c *testConn) Read(b []byte) (int, error) { return c.r.Read(b) }
+
+func (c *testConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func TestFollow(t *testing.T) {
+	var (
+		out, raw bytes.Buffer
+		conns    []*testConn
+		dials    int
+		stop     = make(chan struct{})
+	)
+	tl := tail{out: &out, raw: &raw}
+	tl.follow(func() (conn, error) {
+		dials++
+		switch dials {
+		case 1:
+			c := newTestConn("# aprsc", "N0CALL>APRS:>first", "N0CALL>APRS:!4903.50X/07201.75W-", "invalid")
+			conns = append(conns, c)
+			return c, nil
+		case 2:
+			return nil, io.ErrUnexpectedEOF
+		default:
+			c := newTestConn("N0CALL>APRS:>second")
+			conns = append(conns, c)
+			close(stop)
+			return c, nil
+		}
+	}, backoff{min: time.Millisecond, max: 10 * time.Millisecond}, stop)
+
+	if dials != 3 {
+		t.Errorf("expected 3 dials, got %d", dials)
+	}
+	for i, c := range conns {
+		c.mu.Lock()
+		closed := c.closed
+		c.mu.Unlock()
+		if !closed {
+			t.Errorf("connection %d was not closed", i)
+		}
+	}
+	for _, want := range []string{
+		"[>] first",
+		"N0CALL>APRS:!4903.50X/07201.75W- (aprs: '!' latitude at offset 20: invalid position)",
+		"invalid (aprs: header at offset 7: invalid packet)",
+		"[>] second",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+	if n := strings.Count(raw.String(), "\n"); n != 4 {
+		t.Errorf("expected 3 raw lines, got %d:\n%s", n, raw.String())
+	}
+}
+
+func TestDialServerTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer l.Close()
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		tc := textproto.NewConn(c)
+		if _, err = tc.ReadLine(); err != nil {
+			return
+		}
+		tc.PrintfLine("# logresp N0CALL unverified, server T2TEST")
+		tc.PrintfLine("N0CALL>APRS:>test")
+		// Go silent, like a half-open connection.
+		time.Sleep(5 * time.Second)
+	}()
+
+	c, err := dialServer(l.Addr().String(), "N0CALL", -1, "", 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var out bytes.Buffer
+	tl := tail{out: &out, raw: io.Discard}
+	if err = tl.run(c); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("expected %v, got %v", os.ErrDeadlineExceeded, err)
+	}
+	if !strings.Contains(out.String(), "[>] test") {
+		t.Errorf("expected the packet to be shown, got %q", out.String())
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// rotatingFile is a log file that is rotated when it exceeds a size, keeping
+// a number of old files with a numeric suffix.
+type rotatingFile struct {
+	mu   sync.Mutex
+	name string
+	size int64
+	keep int
+	file *os.File
+	n    int64
+}
+
+func openRotatingFile(name string, size int64, keep int) (*rotatingFile, error) {
+	r := &rotatingFile{name: name, size: size, keep: keep}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	i, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.n = f, i.Size()
+	return nil
+}
+
+// rotate shifts the old files and starts a new file. The current file is
+// kept open until the new file is opened, so writes continue to it if
+// rotation fails.
+func (r *rotatingFile) rotate() error {
+	if err := r.shift(); err != nil {
+		return err
+	}
+	old := r.file
+	if err := r.open(); err != nil {
+		return err
+	}
+	return old.Close()
+}
+
+func (r *rotatingFile) shift() error {
+	for i := r.keep - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", r.name, i), fmt.Sprintf("%s.%d", r.name, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if r.keep > 0 {
+		return os.Rename(r.name, r.name+".1")
+	}
+	return os.Remove(r.name)
+}
+
+// Write appends to the file, rotating it first if it would exceed the size.
+// If rotation fails the data is still written and the error is returned, the
+// rotation is retried once another size worth of data has been written.
+func (r *rotatingFile) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rerr error
+	if r.size > 0 && r.n > 0 && r.n+int64(len(b)) > r.size {
+		if rerr = r.rotate(); rerr != nil {
+			r.n = 0
+		}
+	}
+	n, err := r.file.Write(b)
+	r.n += int64(n)
+	if err == nil {
+		err = rerr
+	}
+	return n, err
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
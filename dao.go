@@ -0,0 +1,144 @@
+package aprs
+
+import (
+	"fmt"
+	"math"
+)
+
+// Position precision in meters.
+const (
+	precisionMinute       = 1852.0                  // 1 minute
+	precisionUncompressed = precisionMinute / 100   // 0.01 minute
+	precisionDAO          = precisionMinute / 1000  // 0.001 minute
+	precisionDAOBase91    = precisionMinute / 10000 // ~0.0001 minute
+	precisionCompressed   = 0.291
+)
+
+// DAO is the !DAO! datum and precision extension.
+type DAO struct {
+	Datum         byte // Datum, uppercase for human readable, lowercase for base91 offsets
+	LatitudeCode  byte
+	LongitudeCode byte
+}
+
+// ParseDAO finds the last !DAO! extension in s, it returns the extension and
+// s with the extension removed.
+func ParseDAO(s string) (DAO, string, bool) {
+	// APRS 1.2 DATUM, http://www.aprs.org/aprs12/datum.txt
+
+	for i := len(s) - 5; i >= 0; i-- {
+		if s[i] != '!' || s[i+4] != '!' {
+			continue
+		}
+		d := DAO{Datum: s[i+1], LatitudeCode: s[i+2], LongitudeCode: s[i+3]}
+		if d.valid() {
+			return d, s[:i] + s[i+5:], true
+		}
+	}
+	return DAO{}, s, false
+}
+
+func (d DAO) valid() bool {
+	switch {
+	case d.Datum < 0x21 || d.Datum > 0x7b:
+		return false
+	case d.LatitudeCode == ' ' && d.LongitudeCode == ' ':
+		return true
+	case d.Datum >= 'A' && d.Datum <= 'Z':
+		return isDigit(d.LatitudeCode) && isDigit(d.LongitudeCode)
+	case d.Datum >= 'a' && d.Datum <= 'z':
+		return d.LatitudeCode >= 0x21 && d.LatitudeCode <= 0x7b &&
+			d.LongitudeCode >= 0x21 && d.LongitudeCode <= 0x7b
+	default:
+		return false
+	}
+}
+
+// IsBase91 returns true if the offsets are base91 encoded.
+func (d DAO) IsBase91() bool {
+	return d.Datum >= 'a' && d.Datum <= 'z'
+}
+
+// offset returns the latitude and longitude offsets in minutes, and the
+// resulting precision in meters.
+func (d DAO) offset() (float64, float64, float64, bool) {
+	switch {
+	case d.LatitudeCode == ' ' || d.LongitudeCode == ' ':
+		return 0, 0, 0, false
+	case d.IsBase91():
+		return float64(d.LatitudeCode-33) / 91 * 0.01,
+			float64(d.LongitudeCode-33) / 91 * 0.01,
+			precisionDAOBase91, true
+	default:
+		return float64(d.LatitudeCode-'0') * 0.001,
+			float64(d.LongitudeCode-'0') * 0.001,
+			precisionDAO, true
+	}
+}
+
+// Apply records the datum and adds the extra precision to an uncompressed
+// position.
+func (d DAO) Apply(pos *Position) {
+	pos.Datum = d.Datum
+	if pos.Compressed || pos.Ambiguity > 0 {
+		return
+	}
+
+	lat, lng, precision, ok := d.offset()
+	if !ok {
+		return
+	}
+	if pos.Latitude < 0 {
+		pos.Latitude -= lat / 60
+	} else {
+		pos.Latitude += lat / 60
+	}
+	if pos.Longitude < 0 {
+		pos.Longitude -= lng / 60
+	} else {
+		pos.Longitude += lng / 60
+	}
+	pos.Precision = precision
+}
+
+// String formats the extension as !DAO!.
+func (d DAO) String() string {
+	return fmt.Sprintf("!%c%c%c!", d.Datum, d.LatitudeCode, d.LongitudeCode)
+}
+
+// FormatDAO formats the !DAO! extension carrying the precision that
+// FormatUncompressedPosition drops, using the datum of the position. It
+// returns an empty string if the position has no datum.
+func FormatDAO(pos Position) string {
+	if pos.Datum == 0 {
+		return ""
+	}
+
+	d := DAO{Datum: pos.Datum}
+	steps := d.steps()
+	_, lat := daoSplit(pos.Latitude, steps)
+	_, lng := daoSplit(pos.Longitude, steps)
+	if d.IsBase91() {
+		d.LatitudeCode = base91[lat]
+		d.LongitudeCode = base91[lng]
+	} else {
+		d.LatitudeCode = '0' + byte(lat)
+		d.LongitudeCode = '0' + byte(lng)
+	}
+	return d.String()
+}
+
+// steps is the number of DAO offsets per hundredth of a minute.
+func (d DAO) steps() int {
+	if d.IsBase91() {
+		return 91
+	}
+	return 10
+}
+
+// daoSplit splits the absolute value of a coordinate into hundredths of
+// minutes, and the DAO offset in 1/steps of hundredths of minutes.
+func daoSplit(v float64, steps int) (int, int) {
+	n := int(math.Round(math.Abs(v) * 6000 * float64(steps)))
+	return n / steps, n % steps
+}
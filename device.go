@@ -0,0 +1,235 @@
+package aprs
+
+import (
+	_ "embed" // Device registry
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The embedded registry is updated from the upstream aprs-deviceid registry
+// with go generate, the file keeps its upstream attribution and license.
+//go:generate curl -fsSL -o tocalls.yaml https://raw.githubusercontent.com/aprsorg/aprs-deviceid/main/tocalls.yaml
+
+//go:embed tocalls.yaml
+var tocallsYAML string
+
+// Device identifies the software or hardware that originated a packet.
+type Device struct {
+	Vendor  string
+	Model   string
+	Class   string // Class identifier, see DeviceRegistry.Class
+	OS      string
+	Version string // Characters of the tocall matched by wildcards, if any
+}
+
+// DeviceClass describes a class of devices.
+type DeviceClass struct {
+	Class       string `yaml:"class"`
+	Shown       string `yaml:"shown"`
+	Description string `yaml:"description"`
+}
+
+// DeviceEntry is a registry entry, identified by a tocall or by Mic-E prefix
+// and suffix bytes.
+type DeviceEntry struct {
+	Tocall string `yaml:"tocall"`
+	Prefix string `yaml:"prefix"`
+	Suffix string `yaml:"suffix"`
+	Vendor string `yaml:"vendor"`
+	Model  string `yaml:"model"`
+	Class  string `yaml:"class"`
+	OS     string `yaml:"os"`
+}
+
+func (e DeviceEntry) device() Device {
+	return Device{Vendor: e.Vendor, Model: e.Model, Class: e.Class, OS: e.OS}
+}
+
+// DeviceRegistry maps destination tocalls and Mic-E type bytes to devices,
+// see https://github.com/aprsorg/aprs-deviceid
+type DeviceRegistry struct {
+	Classes    []DeviceClass `yaml:"classes"`
+	Tocalls    []DeviceEntry `yaml:"tocalls"`
+	MicE       []DeviceEntry `yaml:"mice"`
+	MicELegacy []DeviceEntry `yaml:"micelegacy"`
+}
+
+// ParseDeviceRegistry parses a registry in tocalls.yaml format.
+func ParseDeviceRegistry(r io.Reader) (*DeviceRegistry, error) {
+	var reg DeviceRegistry
+	if err := yaml.NewDecoder(r).Decode(&reg); err != nil {
+		return nil, err
+	}
+	return &reg, nil
+}
+
+var (
+	devices     *DeviceRegistry
+	devicesErr  error
+	devicesOnce sync.Once
+	devicesMu   sync.RWMutex
+)
+
+// loadDevices parses the embedded registry on first use.
+func loadDevices() {
+	devicesOnce.Do(func() {
+		reg, err := ParseDeviceRegistry(strings.NewReader(tocallsYAML))
+		devicesMu.Lock()
+		devices, devicesErr = reg, err
+		devicesMu.Unlock()
+	})
+}
+
+// Devices returns the registry used by Packet.Device, which is the embedded
+// registry unless replaced by LoadDeviceRegistry.
+func Devices() (*DeviceRegistry, error) {
+	loadDevices()
+	devicesMu.RLock()
+	defer devicesMu.RUnlock()
+	return devices, devicesErr
+}
+
+// LoadDeviceRegistry replaces the registry used by Packet.Device with the
+// contents of a local tocalls.yaml file.
+func LoadDeviceRegistry(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reg, err := ParseDeviceRegistry(f)
+	if err != nil {
+		return err
+	}
+
+	loadDevices()
+	devicesMu.Lock()
+	devices, devicesErr = reg, nil
+	devicesMu.Unlock()
+	return nil
+}
+
+// Class returns the device class with the identifier.
+func (reg *DeviceRegistry) Class(class string) (DeviceClass, bool) {
+	for _, c := range reg.Classes {
+		if c.Class == class {
+			return c, true
+		}
+	}
+	return DeviceClass{}, false
+}
+
+// ByTocall returns the device for a destination call. Tocalls may contain '?'
+// wildcards, matching any single character, or end in a '*' wildcard,
+// matching the remainder. The match with the fewest wildcards wins.
+func (reg *DeviceRegistry) ByTocall(call string) (Device, bool) {
+	var (
+		best    Device
+		found   bool
+		fewest  = len(call) + 1
+		matched string
+	)
+	for _, e := range reg.Tocalls {
+		version, wild, ok := matchTocall(e.Tocall, call)
+		if ok && wild < fewest {
+			best, found, fewest, matched = e.device(), true, wild, version
+		}
+	}
+	best.Version = matched
+	return best, found
+}
+
+func matchTocall(pattern, call string) (string, int, bool) {
+	var (
+		version []byte
+		wild    int
+	)
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '*':
+			return string(version) + call[i:], wild + len(call) - i, true
+		case i >= len(call):
+			return "", 0, false
+		case pattern[i] == '?':
+			version = append(version, call[i])
+			wild++
+		case pattern[i] != call[i]:
+			return "", 0, false
+		}
+	}
+	if len(call) != len(pattern) {
+		return "", 0, false
+	}
+	return string(version), wild, true
+}
+
+// ByMicE returns the device for the status text of a Mic-E packet, which
+// starts with a type byte and may end in a device suffix.
+func (reg *DeviceRegistry) ByMicE(text string) (Device, bool) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 10, page 54 (64 in PDF)
+
+	if len(text) == 0 {
+		return Device{}, false
+	}
+
+	switch text[0] {
+	case '`', '\'': // New style, two byte suffix
+		if len(text) >= 3 {
+			for _, e := range reg.MicE {
+				if e.Suffix != "" && strings.HasSuffix(text, e.Suffix) {
+					return e.device(), true
+				}
+			}
+		}
+
+	case '>', ']': // Kenwood, optional one byte suffix
+		var (
+			best  Device
+			found bool
+		)
+		for _, e := range reg.MicELegacy {
+			if e.Prefix != text[:1] {
+				continue
+			}
+			if e.Suffix == "" && !found {
+				best, found = e.device(), true
+			} else if e.Suffix != "" && len(text) >= 2 && strings.HasSuffix(text, e.Suffix) {
+				return e.device(), true
+			}
+		}
+		return best, found
+	}
+
+	return Device{}, false
+}
+
+// Device identifies the software or hardware that originated the packet, from
+// the Mic-E type bytes or the destination tocall. No device is found if the
+// registry failed to load, see Devices.
+func (p Packet) Device() (Device, bool) {
+	reg, err := Devices()
+	if err != nil {
+		return Device{}, false
+	}
+
+	switch p.Payload.Type() {
+	case '`', '\'':
+		if s := string(p.Payload); len(s) > 9 {
+			if d, ok := reg.ByMicE(s[9:]); ok {
+				return d, true
+			}
+		}
+		// Mic-E encodes the position in the destination, so it is no tocall.
+		return Device{}, false
+	}
+
+	if p.Dst == nil {
+		return Device{}, false
+	}
+	return reg.ByTocall(p.Dst.Call)
+}
@@ -0,0 +1,83 @@
+package aprs
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity of a parse error.
+type Severity int
+
+// Parse error severities.
+const (
+	SeverityFatal   Severity = iota // The packet could not be decoded
+	SeverityWarning                 // Part of the payload was not decoded, the packet is usable
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityFatal:
+		return "fatal"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// ParseError describes where and why a packet failed to decode. The cause is
+// available through errors.Is and errors.As, for example:
+//
+//	var perr *ParseError
+//	if errors.As(err, &perr) && errors.Is(perr, ErrInvalidPosition) {
+//		log.Printf("bad %s at %d", perr.Field, perr.Offset)
+//	}
+type ParseError struct {
+	Offset   int      // Byte offset in the raw packet
+	Field    string   // Field that failed to decode, such as "latitude"
+	DataType DataType // Data type of the payload, 0 for errors in the header
+	Severity Severity
+	Err      error // Cause, such as ErrInvalidPosition or a *strconv.NumError
+}
+
+func (err *ParseError) Error() string {
+	var b strings.Builder
+	b.WriteString("aprs: ")
+	if err.Severity == SeverityWarning {
+		b.WriteString("warning: ")
+	}
+	if err.DataType != 0 {
+		fmt.Fprintf(&b, "%q ", rune(err.DataType))
+	}
+	fmt.Fprintf(&b, "%s at offset %d", err.Field, err.Offset)
+	if err.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(strings.TrimPrefix(err.Err.Error(), "aprs: "))
+	}
+	return b.String()
+}
+
+func (err *ParseError) Unwrap() error { return err.Err }
+
+// Fatal returns true if the packet could not be decoded.
+func (err *ParseError) Fatal() bool { return err.Severity == SeverityFatal }
+
+// fieldError moves the offset of a *ParseError by offset bytes. Other errors
+// are wrapped in a fatal *ParseError of the field at the offset.
+func fieldError(err error, offset int, field string) error {
+	if perr, ok := err.(*ParseError); ok {
+		perr.Offset += offset
+		return perr
+	}
+	return &ParseError{Offset: offset, Field: field, Err: err}
+}
+
+// warn records a recoverable error at an offset in the payload.
+func (p *Packet) warn(offset int, field string, err error) {
+	p.Diagnostics = append(p.Diagnostics, &ParseError{
+		Offset:   offset,
+		Field:    field,
+		Severity: SeverityWarning,
+		Err:      err,
+	})
+}
@@ -0,0 +1,172 @@
+// Package export writes APRS positions as GeoJSON, KML and GPX.
+//
+// Packets are collected per station into tracks, objects and items become
+// waypoints:
+//
+//	c := export.NewCollection()
+//	for _, p := range packets {
+//		c.Add(p, received)
+//	}
+//	export.WriteGPX(w, c)
+package export
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+// Point is a position reported at a time.
+type Point struct {
+	Position aprs.Position
+	Time     time.Time
+	Comment  string
+}
+
+// Track is the sequence of positions reported by a station.
+type Track struct {
+	Station string
+	Symbol  aprs.Symbol
+	Points  []Point
+}
+
+// Waypoint is the last known state of an object or item.
+type Waypoint struct {
+	Name   string
+	Owner  string // Station that sent the object or item
+	Symbol aprs.Symbol
+	Point  Point
+	Item   bool
+	Killed bool
+}
+
+// Collection groups packets into tracks and waypoints.
+type Collection struct {
+	tracks    map[string]*Track
+	waypoints map[string]*Waypoint
+}
+
+// NewCollection returns an empty collection.
+func NewCollection() *Collection {
+	return &Collection{
+		tracks:    make(map[string]*Track),
+		waypoints: make(map[string]*Waypoint),
+	}
+}
+
+// Collect adds all packets from the channel to a new collection, using the
+// time each packet is read as its receive time.
+func Collect(packets <-chan aprs.Packet) *Collection {
+	c := NewCollection()
+	for p := range packets {
+		c.Add(p, time.Now())
+	}
+	return c
+}
+
+// Add a packet received at the time. Packets without a position are ignored,
+// third-party traffic is unwrapped. The packet time stamp or NMEA fix time,
+// if any, is resolved against the receive time.
+func (c *Collection) Add(p aprs.Packet, received time.Time) {
+	p = p.Unwrap()
+	if p.Position == nil || p.Src == nil {
+		return
+	}
+
+	t := packetTime(p, received)
+	point := Point{Position: p.Position.Center(), Time: t, Comment: p.Comment}
+
+	if p.Object != nil {
+		w, ok := c.waypoints[p.Object.Name]
+		if ok && w.Point.Time.After(t) {
+			return
+		}
+		c.waypoints[p.Object.Name] = &Waypoint{
+			Name:   p.Object.Name,
+			Owner:  p.Src.String(),
+			Symbol: p.Symbol,
+			Point:  point,
+			Item:   p.Object.Item,
+			Killed: p.Object.Killed,
+		}
+		return
+	}
+
+	station := p.Src.String()
+	track, ok := c.tracks[station]
+	if !ok {
+		track = &Track{Station: station}
+		c.tracks[station] = track
+	}
+	if p.Symbol != (aprs.Symbol{}) {
+		track.Symbol = p.Symbol
+	}
+	track.Points = append(track.Points, point)
+}
+
+// packetTime returns the time of the packet time stamp or NMEA fix, or the
+// receive time if the packet has neither.
+func packetTime(p aprs.Packet, received time.Time) time.Time {
+	if p.Payload.Timestamp() != "" {
+		if t, _, err := p.ResolveTime(received, nil); err == nil {
+			return t
+		}
+		return received
+	}
+	if p.Time == nil {
+		return received
+	}
+
+	t := p.Time.UTC()
+	if t.Year() > 1 {
+		return t
+	}
+	// Fixes such as $GPGGA only hold the time of day, take the day closest
+	// to the receive time.
+	r := received.UTC()
+	t = time.Date(r.Year(), r.Month(), r.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	switch d := t.Sub(r); {
+	case d > 12*time.Hour:
+		t = t.AddDate(0, 0, -1)
+	case d < -12*time.Hour:
+		t = t.AddDate(0, 0, 1)
+	}
+	return t
+}
+
+// Tracks returns the station tracks sorted by station, with the points in
+// time order.
+func (c *Collection) Tracks() []Track {
+	var tracks = make([]Track, 0, len(c.tracks))
+	for _, t := range c.tracks {
+		track := *t
+		track.Points = append([]Point(nil), t.Points...)
+		sort.SliceStable(track.Points, func(i, j int) bool {
+			return track.Points[i].Time.Before(track.Points[j].Time)
+		})
+		tracks = append(tracks, track)
+	}
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].Station < tracks[j].Station })
+	return tracks
+}
+
+// Waypoints returns the objects and items that are not killed, sorted by
+// name.
+func (c *Collection) Waypoints() []Waypoint {
+	var waypoints []Waypoint
+	for _, w := range c.waypoints {
+		if !w.Killed {
+			waypoints = append(waypoints, *w)
+		}
+	}
+	sort.Slice(waypoints, func(i, j int) bool { return waypoints[i].Name < waypoints[j].Name })
+	return waypoints
+}
+
+func symbolName(s aprs.Symbol) string {
+	if s == (aprs.Symbol{}) {
+		return ""
+	}
+	return s.String()
+}
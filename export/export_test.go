@@ -0,0 +1,133 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var testPackets = []string{
+	"N0CALL-9>APRS:/010925z5207.00N/00507.00E>Driving",
+	"N0CALL-9>APRS:/010920z5206.00N/00506.00E>Parked",
+	"N0CALL-5>GPS:$GPGGA,092800,5210.000,N,00510.000,E,1,08,0.9,10.0,M,46.9,M,,*78",
+	"N0CALL-7>GPS:$GPRMC,092700,A,5211.000,N,00511.000,E,0.0,0.0,010320,,*13",
+	"N0CALL>APRS:;EVENT    *010900z5212.00N/00512.00EEMeeting",
+	"N0CALL>APRS:)AID #2!5213.00N/00513.00E+First aid",
+	"N0CALL>APRS:;GONE     *010900z5214.00N/00514.00E>",
+	"N0CALL>APRS:;GONE     _010905z5214.00N/00514.00E>",
+	"N0CALL>APRS:>No position",
+}
+
+func testCollection(t *testing.T) *Collection {
+	t.Helper()
+	var (
+		c        = NewCollection()
+		received = time.Date(2020, 3, 1, 9, 30, 0, 0, time.UTC)
+	)
+	for _, raw := range testPackets {
+		p, err := aprs.ParsePacket(raw)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		c.Add(p, received)
+	}
+	return c
+}
+
+func testGolden(t *testing.T, name string, write func(io.Writer, *Collection) error) {
+	var b bytes.Buffer
+	if err := write(&b, testCollection(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(golden, b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("output differs from %s:\n%s", golden, b.Bytes())
+	}
+}
+
+func TestWriteGeoJSON(t *testing.T) { testGolden(t, "collection.geojson", WriteGeoJSON) }
+func TestWriteGPX(t *testing.T)     { testGolden(t, "collection.gpx", WriteGPX) }
+func TestWriteKML(t *testing.T)     { testGolden(t, "collection.kml", WriteKML) }
+
+func TestCollection(t *testing.T) {
+	c := testCollection(t)
+
+	tracks := c.Tracks()
+	if len(tracks) != 3 {
+		t.Fatalf("expected 3 tracks, got %+v", tracks)
+	}
+	for _, test := range []struct {
+		Station string
+		Symbol  aprs.Symbol
+		Times   []string
+	}{
+		{"N0CALL-5", aprs.Symbol{}, []string{"2020-03-01T09:28:00Z"}},
+		{"N0CALL-7", aprs.Symbol{}, []string{"2020-03-01T09:27:00Z"}},
+		{"N0CALL-9", aprs.Symbol{'/', '>'}, []string{"2020-03-01T09:20:00Z", "2020-03-01T09:25:00Z"}},
+	} {
+		var track *Track
+		for i := range tracks {
+			if tracks[i].Station == test.Station {
+				track = &tracks[i]
+			}
+		}
+		if track == nil {
+			t.Errorf("%s: no track", test.Station)
+			continue
+		}
+		if track.Symbol != test.Symbol {
+			t.Errorf("%s: expected symbol %q, got %q", test.Station, test.Symbol[:], track.Symbol[:])
+		}
+		if len(track.Points) != len(test.Times) {
+			t.Errorf("%s: expected %d points, got %d", test.Station, len(test.Times), len(track.Points))
+			continue
+		}
+		for i, want := range test.Times {
+			if s := track.Points[i].Time.Format(time.RFC3339); s != want {
+				t.Errorf("%s: point %d: expected time %s, got %s", test.Station, i, want, s)
+			}
+		}
+	}
+
+	waypoints := c.Waypoints()
+	if len(waypoints) != 2 || waypoints[0].Name != "AID #2" || waypoints[1].Name != "EVENT" {
+		t.Errorf("unexpected waypoints %+v", waypoints)
+	}
+}
+
+func TestPacketTime(t *testing.T) {
+	received := time.Date(2020, 3, 1, 0, 10, 0, 0, time.UTC)
+	for _, test := range []struct {
+		Fix, Want string
+	}{
+		{"000500", "2020-03-01T00:05:00Z"},
+		{"235500", "2020-02-29T23:55:00Z"},
+	} {
+		fix, err := time.Parse("150405", test.Fix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := aprs.Packet{Payload: "$GPGGA", Time: &fix}
+		if s := packetTime(p, received).Format(time.RFC3339); s != test.Want {
+			t.Errorf("%s: expected %s, got %s", test.Fix, test.Want, s)
+		}
+	}
+}
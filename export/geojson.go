@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+func geoJSONPoint(p Point) geoJSONGeometry {
+	return geoJSONGeometry{
+		Type:        "Point",
+		Coordinates: [2]float64{p.Position.Longitude, p.Position.Latitude},
+	}
+}
+
+// WriteGeoJSON writes the collection as a GeoJSON FeatureCollection. Tracks
+// become LineString features (or Point features for a single position) with
+// the station, symbol and the times of the positions as properties,
+// waypoints become Point features with the object or item name. The symbol
+// and its description (symbol_name) are omitted if the station sent no symbol.
+func WriteGeoJSON(w io.Writer, c *Collection) error {
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+
+	for _, t := range c.Tracks() {
+		var (
+			coords = make([][2]float64, len(t.Points))
+			times  = make([]string, len(t.Points))
+		)
+		for i, p := range t.Points {
+			coords[i] = [2]float64{p.Position.Longitude, p.Position.Latitude}
+			times[i] = p.Time.UTC().Format(time.RFC3339)
+		}
+
+		f := geoJSONFeature{
+			Type: "Feature",
+			Properties: map[string]interface{}{
+				"station": t.Station,
+				"times":   times,
+				"comment": t.Points[len(t.Points)-1].Comment,
+			},
+		}
+		if t.Symbol[0] != 0 {
+			f.Properties["symbol"] = string(t.Symbol[:])
+			f.Properties["symbol_name"] = symbolName(t.Symbol)
+		}
+		if len(coords) == 1 {
+			f.Geometry = geoJSONPoint(t.Points[0])
+		} else {
+			f.Geometry = geoJSONGeometry{Type: "LineString", Coordinates: coords}
+		}
+		fc.Features = append(fc.Features, f)
+	}
+
+	for _, wp := range c.Waypoints() {
+		f := geoJSONFeature{
+			Type:     "Feature",
+			Geometry: geoJSONPoint(wp.Point),
+			Properties: map[string]interface{}{
+				"name":    wp.Name,
+				"owner":   wp.Owner,
+				"item":    wp.Item,
+				"time":    wp.Point.Time.UTC().Format(time.RFC3339),
+				"comment": wp.Point.Comment,
+			},
+		}
+		if wp.Symbol[0] != 0 {
+			f.Properties["symbol"] = string(wp.Symbol[:])
+			f.Properties["symbol_name"] = symbolName(wp.Symbol)
+		}
+		fc.Features = append(fc.Features, f)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(fc)
+}
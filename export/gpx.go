@@ -0,0 +1,71 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type gpx struct {
+	XMLName   xml.Name   `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Tracks    []gpxTrack `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Time      string  `xml:"time,omitempty"`
+	Name      string  `xml:"name,omitempty"`
+	Comment   string  `xml:"cmt,omitempty"`
+	Symbol    string  `xml:"sym,omitempty"`
+}
+
+type gpxTrack struct {
+	Name    string     `xml:"name"`
+	Comment string     `xml:"cmt,omitempty"`
+	Segment []gpxPoint `xml:"trkseg>trkpt"`
+}
+
+func newGPXPoint(p Point) gpxPoint {
+	return gpxPoint{
+		Latitude:  p.Position.Latitude,
+		Longitude: p.Position.Longitude,
+		Time:      p.Time.UTC().Format(time.RFC3339),
+	}
+}
+
+// WriteGPX writes the collection as GPX 1.1, with a track per station and a
+// waypoint per object or item.
+func WriteGPX(w io.Writer, c *Collection) error {
+	doc := gpx{Version: "1.1", Creator: "go-aprs"}
+
+	for _, wp := range c.Waypoints() {
+		p := newGPXPoint(wp.Point)
+		p.Name = wp.Name
+		p.Comment = wp.Point.Comment
+		p.Symbol = symbolName(wp.Symbol)
+		doc.Waypoints = append(doc.Waypoints, p)
+	}
+
+	for _, t := range c.Tracks() {
+		trk := gpxTrack{Name: t.Station, Comment: symbolName(t.Symbol)}
+		for _, p := range t.Points {
+			trk.Segment = append(trk.Segment, newGPXPoint(p))
+		}
+		doc.Tracks = append(doc.Tracks, trk)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+// SpriteURL are the standard 16x6 symbol sprite sheets, for the primary
+// table, the alternate table and the overlay characters.
+var SpriteURL = [3]string{
+	"https://raw.githubusercontent.com/hessu/aprs-symbols/master/png/aprs-symbols-24-0.png",
+	"https://raw.githubusercontent.com/hessu/aprs-symbols/master/png/aprs-symbols-24-1.png",
+	"https://raw.githubusercontent.com/hessu/aprs-symbols/master/png/aprs-symbols-24-2.png",
+}
+
+// SpriteSize is the size in pixels of the symbols on the SpriteURL sheets.
+var SpriteSize = 24
+
+type kml struct {
+	XMLName  xml.Name    `xml:"http://www.opengis.net/kml/2.2 kml"`
+	GX       string      `xml:"xmlns:gx,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Styles     []kmlStyle     `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	ID   string  `xml:"id,attr"`
+	Icon kmlIcon `xml:"IconStyle>Icon"`
+}
+
+type kmlIcon struct {
+	Href string `xml:"href"`
+	X    int    `xml:"gx:x"`
+	Y    int    `xml:"gx:y"`
+	W    int    `xml:"gx:w"`
+	H    int    `xml:"gx:h"`
+}
+
+type kmlPlacemark struct {
+	Name        string      `xml:"name"`
+	Description string      `xml:"description,omitempty"`
+	TimeStamp   *kmlWhen    `xml:"TimeStamp"`
+	StyleURL    string      `xml:"styleUrl,omitempty"`
+	Point       *kmlPoint   `xml:"Point"`
+	Track       *kmlGXTrack `xml:"gx:Track"`
+}
+
+type kmlWhen struct {
+	When string `xml:"when"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlGXTrack struct {
+	When  []string `xml:"when"`
+	Coord []string `xml:"gx:coord"`
+}
+
+func kmlCoordinates(p aprs.Position) string {
+	return fmt.Sprintf("%f,%f", p.Longitude, p.Latitude)
+}
+
+// kmlStyles collects an icon style per symbol sprite.
+type kmlStyles map[string]kmlStyle
+
+func (s kmlStyles) url(sym aprs.Symbol) string {
+	sp, ok := sym.Sprite()
+	if !ok {
+		return ""
+	}
+	id := fmt.Sprintf("sym-%d-%02x", sp.Sheet, sym[1])
+	if _, ok := s[id]; !ok {
+		x, y := sp.Offset(SpriteSize)
+		// KML counts the y offset from the bottom of the image.
+		s[id] = kmlStyle{ID: id, Icon: kmlIcon{
+			Href: SpriteURL[sp.Sheet],
+			X:    x,
+			Y:    6*SpriteSize - y - SpriteSize,
+			W:    SpriteSize,
+			H:    SpriteSize,
+		}}
+	}
+	return "#" + id
+}
+
+// WriteKML writes the collection as KML, with a placemark per station that
+// holds its track, and a placemark per object or item. Placemarks use the
+// symbol icons from the SpriteURL sheets. Overlay characters are not drawn.
+func WriteKML(w io.Writer, c *Collection) error {
+	var (
+		doc    = kml{GX: "http://www.google.com/kml/ext/2.2"}
+		styles = make(kmlStyles)
+	)
+	doc.Document.Name = "APRS"
+
+	for _, t := range c.Tracks() {
+		pm := kmlPlacemark{
+			Name:        t.Station,
+			Description: t.Points[len(t.Points)-1].Comment,
+			StyleURL:    styles.url(t.Symbol),
+		}
+		if len(t.Points) == 1 {
+			pm.TimeStamp = &kmlWhen{t.Points[0].Time.UTC().Format(time.RFC3339)}
+			pm.Point = &kmlPoint{kmlCoordinates(t.Points[0].Position)}
+		} else {
+			pm.Track = new(kmlGXTrack)
+			for _, p := range t.Points {
+				pm.Track.When = append(pm.Track.When, p.Time.UTC().Format(time.RFC3339))
+				pm.Track.Coord = append(pm.Track.Coord, strings.Replace(kmlCoordinates(p.Position), ",", " ", 1))
+			}
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+	}
+
+	for _, wp := range c.Waypoints() {
+		doc.Document.Placemarks = append(doc.Document.Placemarks, kmlPlacemark{
+			Name:        wp.Name,
+			Description: wp.Point.Comment,
+			TimeStamp:   &kmlWhen{wp.Point.Time.UTC().Format(time.RFC3339)},
+			StyleURL:    styles.url(wp.Symbol),
+			Point:       &kmlPoint{kmlCoordinates(wp.Point.Position)},
+		})
+	}
+
+	for _, s := range styles {
+		doc.Document.Styles = append(doc.Document.Styles, s)
+	}
+	sort.Slice(doc.Document.Styles, func(i, j int) bool {
+		return doc.Document.Styles[i].ID < doc.Document.Styles[j].ID
+	})
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,171 @@
+package aprs
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrVincentyConvergence signals that the Vincenty formula failed to
+	// converge, which happens for nearly antipodal positions.
+	ErrVincentyConvergence = errors.New("aprs: Vincenty formula failed to converge")
+)
+
+// Mean earth radius in meters.
+const meanEarthRadius = 6371008.8
+
+// WGS-84 ellipsoid.
+const (
+	wgs84A = 6378137.0
+	wgs84F = 1 / 298.257223563
+	wgs84B = wgs84A * (1 - wgs84F)
+)
+
+func radians(d float64) float64 { return d * math.Pi / 180 }
+func degrees(r float64) float64 { return r * 180 / math.Pi }
+
+// DistanceTo returns the great circle distance to another position.
+func (pos Position) DistanceTo(other Position) Distance {
+	var (
+		lat1 = radians(pos.Latitude)
+		lat2 = radians(other.Latitude)
+		dLat = lat2 - lat1
+		dLng = radians(other.Longitude - pos.Longitude)
+		h    = math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLng/2), 2)
+	)
+	return Distance(2 * meanEarthRadius * math.Asin(math.Sqrt(h)))
+}
+
+// BearingTo returns the initial great circle bearing in degrees to another
+// position.
+func (pos Position) BearingTo(other Position) float64 {
+	var (
+		lat1 = radians(pos.Latitude)
+		lat2 = radians(other.Latitude)
+		dLng = radians(other.Longitude - pos.Longitude)
+		y    = math.Sin(dLng) * math.Cos(lat2)
+		x    = math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLng)
+	)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// Destination returns the position reached when travelling the distance
+// along a great circle with the initial bearing in degrees.
+func (pos Position) Destination(bearing float64, distance Distance) Position {
+	var (
+		delta = float64(distance) / meanEarthRadius
+		theta = radians(bearing)
+		lat1  = radians(pos.Latitude)
+		lng1  = radians(pos.Longitude)
+		lat2  = math.Asin(math.Sin(lat1)*math.Cos(delta) + math.Cos(lat1)*math.Sin(delta)*math.Cos(theta))
+		lng2  = lng1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(lat1), math.Cos(delta)-math.Sin(lat1)*math.Sin(lat2))
+	)
+	return Position{
+		Latitude:  degrees(lat2),
+		Longitude: normalizeLongitude(degrees(lng2)),
+	}
+}
+
+// Midpoint returns the half-way point along the great circle to another
+// position.
+func (pos Position) Midpoint(other Position) Position {
+	var (
+		lat1 = radians(pos.Latitude)
+		lat2 = radians(other.Latitude)
+		lng1 = radians(pos.Longitude)
+		dLng = radians(other.Longitude - pos.Longitude)
+		bx   = math.Cos(lat2) * math.Cos(dLng)
+		by   = math.Cos(lat2) * math.Sin(dLng)
+		lat3 = math.Atan2(math.Sin(lat1)+math.Sin(lat2), math.Sqrt(math.Pow(math.Cos(lat1)+bx, 2)+by*by))
+		lng3 = lng1 + math.Atan2(by, math.Cos(lat1)+bx)
+	)
+	return Position{
+		Latitude:  degrees(lat3),
+		Longitude: normalizeLongitude(degrees(lng3)),
+	}
+}
+
+// Within returns true if the position is inside the box.
+func (pos Position) Within(box Box) bool {
+	return box.Contains(pos)
+}
+
+// WithinRadius returns true if the position is within the distance of the
+// centre.
+func (pos Position) WithinRadius(center Position, radius Distance) bool {
+	return pos.DistanceTo(center) <= radius
+}
+
+// BoxAround returns the box enclosing the circle with the radius around the
+// position.
+func BoxAround(center Position, radius Distance) Box {
+	var (
+		dLat = degrees(float64(radius) / meanEarthRadius)
+		dLng = dLat / math.Cos(radians(center.Latitude))
+	)
+	return Box{
+		South: math.Max(center.Latitude-dLat, -90),
+		West:  math.Max(center.Longitude-dLng, -180),
+		North: math.Min(center.Latitude+dLat, 90),
+		East:  math.Min(center.Longitude+dLng, 180),
+	}
+}
+
+func normalizeLongitude(lng float64) float64 {
+	return math.Mod(lng+540, 360) - 180
+}
+
+// VincentyDistanceTo returns the distance to another position on the WGS-84
+// ellipsoid, which is accurate to within a millimeter, but slower than
+// DistanceTo.
+func (pos Position) VincentyDistanceTo(other Position) (Distance, error) {
+	var (
+		L          = radians(other.Longitude - pos.Longitude)
+		U1         = math.Atan((1 - wgs84F) * math.Tan(radians(pos.Latitude)))
+		U2         = math.Atan((1 - wgs84F) * math.Tan(radians(other.Latitude)))
+		sinU1      = math.Sin(U1)
+		cosU1      = math.Cos(U1)
+		sinU2      = math.Sin(U2)
+		cosU2      = math.Cos(U2)
+		lambda     = L
+		sinSigma   float64
+		cosSigma   float64
+		sigma      float64
+		cosSqAlpha float64
+		cos2SigmaM float64
+	)
+
+	for i := 0; ; i++ {
+		if i == 200 {
+			return 0, ErrVincentyConvergence
+		}
+		sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+		sinSigma = math.Sqrt(math.Pow(cosU2*sinLambda, 2) + math.Pow(cosU1*sinU2-sinU1*cosU2*cosLambda, 2))
+		if sinSigma == 0 {
+			return 0, nil // Coincident points
+		}
+		cosSigma = sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha*sinAlpha
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		} else {
+			cos2SigmaM = 0 // Equatorial line
+		}
+		C := wgs84F / 16 * cosSqAlpha * (4 + wgs84F*(4-3*cosSqAlpha))
+		prev := lambda
+		lambda = L + (1-C)*wgs84F*sinAlpha*(sigma+C*sinSigma*(cos2SigmaM+C*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) < 1e-12 {
+			break
+		}
+	}
+
+	var (
+		u2         = cosSqAlpha * (wgs84A*wgs84A - wgs84B*wgs84B) / (wgs84B * wgs84B)
+		A          = 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+		B          = u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+		deltaSigma = B * sinSigma * (cos2SigmaM + B/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-B/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+	)
+	return Distance(wgs84B * A * (sigma - deltaSigma)), nil
+}
@@ -0,0 +1,497 @@
+package aprs
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonPacket is the JSON representation of a Packet.
+type jsonPacket struct {
+	Raw          string        `json:"raw,omitempty"`
+	RawBytes     []byte        `json:"raw_base64,omitempty"`
+	Src          *Address      `json:"src,omitempty"`
+	Dst          *Address      `json:"dst,omitempty"`
+	Path         Path          `json:"path,omitempty"`
+	Type         DataType      `json:"type,omitempty"`
+	Payload      string        `json:"payload,omitempty"`
+	PayloadBytes []byte        `json:"payload_base64,omitempty"`
+	Position     *Position     `json:"position,omitempty"`
+	Time         *time.Time    `json:"time,omitempty"`
+	Altitude     Altitude      `json:"altitude,omitempty"`
+	Velocity     *jsonVelocity `json:"velocity,omitempty"`
+	Wind         *jsonWind     `json:"wind,omitempty"`
+	PHG          string        `json:"phg,omitempty"`
+	DFS          string        `json:"dfs,omitempty"`
+	Range        Distance      `json:"range,omitempty"`
+	Symbol       *Symbol       `json:"symbol,omitempty"`
+	Comment      string        `json:"comment,omitempty"`
+	Status       *jsonStatus   `json:"status,omitempty"`
+	Message      *jsonMessage  `json:"message,omitempty"`
+	Query        *jsonQuery    `json:"query,omitempty"`
+	Capabilities Capabilities  `json:"capabilities,omitempty"`
+	ThirdParty   *Packet       `json:"third_party,omitempty"`
+	Fix          *jsonFix      `json:"fix,omitempty"`
+	Weather      *jsonWeather  `json:"weather,omitempty"`
+	DF           *jsonDF       `json:"df,omitempty"`
+	Area         *jsonArea     `json:"area,omitempty"`
+	Signpost     string        `json:"signpost,omitempty"`
+	Object       *jsonObject   `json:"object,omitempty"`
+	Diagnostics  []jsonDiag    `json:"diagnostics,omitempty"`
+}
+
+type jsonVelocity struct {
+	Course float64 `json:"course"`
+	Speed  Speed   `json:"speed"`
+}
+
+type jsonWind struct {
+	Direction float64 `json:"direction"`
+	Speed     Speed   `json:"speed"`
+	Gust      Speed   `json:"gust"`
+}
+
+type jsonStatus struct {
+	Text    string `json:"text"`
+	Locator string `json:"locator,omitempty"`
+	Symbol  Symbol `json:"symbol"`
+	Beam    string `json:"beam,omitempty"`
+}
+
+type jsonMessage struct {
+	Addressee string `json:"addressee"`
+	Text      string `json:"text"`
+	ID        string `json:"id,omitempty"`
+}
+
+type jsonQuery struct {
+	Type      string         `json:"type"`
+	Directed  bool           `json:"directed"`
+	Target    string         `json:"target,omitempty"`
+	Footprint *jsonFootprint `json:"footprint,omitempty"`
+}
+
+type jsonFootprint struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Radius    Distance `json:"radius"`
+}
+
+type jsonFix struct {
+	Sentence   string `json:"sentence"`
+	Valid      bool   `json:"valid"`
+	Quality    int    `json:"quality"`
+	Satellites int    `json:"satellites"`
+}
+
+type jsonWeather struct {
+	Temperature *Temperature `json:"temperature,omitempty"`
+	Humidity    *float64     `json:"humidity,omitempty"`
+	Pressure    *Pressure    `json:"pressure,omitempty"`
+	RainTotal   *Distance    `json:"rain_total,omitempty"`
+	RainToday   *Distance    `json:"rain_today,omitempty"`
+}
+
+type jsonDF struct {
+	Bearing float64 `json:"bearing"`
+	NRQ     string  `json:"nrq"`
+}
+
+type jsonArea struct {
+	Type            int     `json:"type"`
+	LatitudeOffset  float64 `json:"latitude_offset"`
+	LongitudeOffset float64 `json:"longitude_offset"`
+	Color           string  `json:"color"`
+	LineWidth       int     `json:"line_width,omitempty"`
+}
+
+type jsonObject struct {
+	Name   string `json:"name"`
+	Killed bool   `json:"killed"`
+	Item   bool   `json:"item"`
+}
+
+type jsonDiag struct {
+	Offset   int    `json:"offset"`
+	Field    string `json:"field"`
+	Severity string `json:"severity"`
+	Error    string `json:"error"`
+}
+
+func codes(b ...byte) string {
+	if b[0] == 0 {
+		return ""
+	}
+	return string(b)
+}
+
+func splitCodes(s string, b ...*byte) error {
+	if s == "" {
+		return nil
+	}
+	if len(s) != len(b) {
+		return errors.New("aprs: invalid codes " + s)
+	}
+	for i := range b {
+		*b[i] = s[i]
+	}
+	return nil
+}
+
+// MarshalJSON encodes the packet as JSON, fields that are not set are
+// omitted. Measurements are in SI units (meters, meters per second, °C, Pa),
+// angles in degrees and times in RFC 3339 format:
+//
+//	{
+//	  "raw":          "N0CALL-9>APRS,WIDE1-1:!4903.50N/07201.75W>",
+//	  "src":          "N0CALL-9",
+//	  "dst":          "APRS",
+//	  "path":         ["WIDE1-1"],
+//	  "type":         "!",
+//	  "payload":      "!4903.50N/07201.75W>",
+//	  "position":     {"latitude": 49.0583, "longitude": -72.0292, "ambiguity": 0,
+//	                   "symbol": "/>", "compressed": false, "datum": "W", "precision": 18.52},
+//	  "time":         "2020-03-01T09:30:00Z",
+//	  "altitude":     376,
+//	  "velocity":     {"course": 88, "speed": 18.52},
+//	  "wind":         {"direction": 220, "speed": 2.2, "gust": 2.7},
+//	  "phg":          "5132",
+//	  "dfs":          "2360",
+//	  "range":        80467,
+//	  "symbol":       "/>",
+//	  "comment":      "Test",
+//	  "status":       {"text": "", "locator": "IO91SX", "symbol": "/G", "beam": "JK"},
+//	  "message":      {"addressee": "N0CALL", "text": "Hello", "id": "1"},
+//	  "query":        {"type": "APRS", "directed": false, "target": "",
+//	                   "footprint": {"latitude": 52, "longitude": 5, "radius": 16093}},
+//	  "capabilities": {"IGATE": "", "MSG_CNT": "10"},
+//	  "third_party":  {...},
+//	  "fix":          {"sentence": "GPRMC", "valid": true, "quality": 0, "satellites": 0},
+//	  "weather":      {"temperature": 25, "humidity": 50, "pressure": 101325,
+//	                   "rain_total": 0.01, "rain_today": 0},
+//	  "df":           {"bearing": 88, "nrq": "368"},
+//	  "area":         {"type": 1, "latitude_offset": 0.49, "longitude_offset": 26.01,
+//	                   "color": "/", "line_width": 5},
+//	  "signpost":     "05",
+//	  "object":       {"name": "LEADER", "killed": false, "item": false},
+//	  "diagnostics":  [{"offset": 35, "field": "range", "severity": "warning",
+//	                    "error": "strconv.ParseFloat: parsing \"12ab\": invalid syntax"}]
+//	}
+//
+// If the raw packet or the payload is not valid UTF-8, such as binary Mic-E
+// data, its exact bytes are added in base64 as "raw_base64" and
+// "payload_base64", and take precedence over the string when unmarshalling.
+//
+// The type is derived from the payload and ignored when unmarshalling. The
+// decoded fields are taken as they are, so a packet can be built from JSON
+// without a payload being parsed. Diagnostics keep the text of their cause.
+func (p Packet) MarshalJSON() ([]byte, error) {
+	j := jsonPacket{
+		Raw:          p.Raw,
+		Src:          p.Src,
+		Dst:          p.Dst,
+		Path:         p.Path,
+		Payload:      string(p.Payload),
+		Position:     p.Position,
+		Time:         p.Time,
+		Altitude:     p.Altitude,
+		PHG:          codes(p.PHG.PowerCode, p.PHG.HeightCode, p.PHG.GainCode, p.PHG.DirectivityCode),
+		DFS:          codes(p.DFS.StrengthCode, p.DFS.HeightCode, p.DFS.GainCode, p.DFS.DirectivityCode),
+		Range:        p.Range,
+		Comment:      p.Comment,
+		Capabilities: p.Capabilities,
+		ThirdParty:   p.ThirdParty,
+		Signpost:     p.Signpost,
+	}
+	if !utf8.ValidString(p.Raw) {
+		j.RawBytes = []byte(p.Raw)
+	}
+	if !utf8.ValidString(string(p.Payload)) {
+		j.PayloadBytes = []byte(p.Payload)
+	}
+	if p.Symbol != (Symbol{}) {
+		j.Symbol = &p.Symbol
+	}
+	if len(p.Payload) > 0 {
+		j.Type = p.Payload.Type()
+	}
+	if p.Velocity != (Velocity{}) {
+		j.Velocity = &jsonVelocity{p.Velocity.Course, p.Velocity.Speed}
+	}
+	if p.Wind != (Wind{}) {
+		j.Wind = &jsonWind{p.Wind.Direction, p.Wind.Speed, p.Wind.Gust}
+	}
+	if s := p.Status; s != nil {
+		j.Status = &jsonStatus{s.Text, s.Locator, s.Symbol, codes(s.Beam.HeadingCode, s.Beam.PowerCode)}
+	}
+	if m := p.Message; m != nil {
+		j.Message = &jsonMessage{m.Addressee, m.Text, m.ID}
+	}
+	if q := p.Query; q != nil {
+		j.Query = &jsonQuery{Type: q.Type, Directed: q.Directed, Target: q.Target}
+		if f := q.Footprint; f != nil {
+			j.Query.Footprint = &jsonFootprint{f.Latitude, f.Longitude, f.Radius}
+		}
+	}
+	if f := p.Fix; f != nil {
+		j.Fix = &jsonFix{f.Sentence, f.Valid, f.Quality, f.Satellites}
+	}
+	if w := p.Weather; w != nil {
+		j.Weather = &jsonWeather{w.Temperature, w.Humidity, w.Pressure, w.RainTotal, w.RainToday}
+	}
+	if d := p.DF; d != nil {
+		j.DF = &jsonDF{d.Bearing, codes(d.NumberCode, d.RangeCode, d.QualityCode)}
+	}
+	if a := p.Area; a != nil {
+		j.Area = &jsonArea{a.Type, a.LatitudeOffset, a.LongitudeOffset, codes(a.Color), a.LineWidth}
+	}
+	if o := p.Object; o != nil {
+		j.Object = &jsonObject{o.Name, o.Killed, o.Item}
+	}
+	for _, d := range p.Diagnostics {
+		var cause string
+		if d.Err != nil {
+			cause = strings.TrimPrefix(d.Err.Error(), "aprs: ")
+		}
+		j.Diagnostics = append(j.Diagnostics, jsonDiag{d.Offset, d.Field, d.Severity.String(), cause})
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a packet in the format of MarshalJSON.
+func (p *Packet) UnmarshalJSON(b []byte) error {
+	var j jsonPacket
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	*p = Packet{
+		Raw:          j.Raw,
+		Src:          j.Src,
+		Dst:          j.Dst,
+		Path:         j.Path,
+		Payload:      Payload(j.Payload),
+		Position:     j.Position,
+		Time:         j.Time,
+		Altitude:     j.Altitude,
+		Range:        j.Range,
+		Comment:      j.Comment,
+		Capabilities: j.Capabilities,
+		ThirdParty:   j.ThirdParty,
+		Signpost:     j.Signpost,
+	}
+	if j.RawBytes != nil {
+		p.Raw = string(j.RawBytes)
+	}
+	if j.PayloadBytes != nil {
+		p.Payload = Payload(j.PayloadBytes)
+	}
+	if j.Symbol != nil {
+		p.Symbol = *j.Symbol
+	}
+	if err := splitCodes(j.PHG, &p.PHG.PowerCode, &p.PHG.HeightCode, &p.PHG.GainCode, &p.PHG.DirectivityCode); err != nil {
+		return err
+	}
+	if err := splitCodes(j.DFS, &p.DFS.StrengthCode, &p.DFS.HeightCode, &p.DFS.GainCode, &p.DFS.DirectivityCode); err != nil {
+		return err
+	}
+	if v := j.Velocity; v != nil {
+		p.Velocity = Velocity{v.Course, v.Speed}
+	}
+	if w := j.Wind; w != nil {
+		p.Wind = Wind{w.Direction, w.Speed, w.Gust}
+	}
+	if s := j.Status; s != nil {
+		p.Status = &Status{Text: s.Text, Locator: s.Locator, Symbol: s.Symbol}
+		if err := splitCodes(s.Beam, &p.Status.Beam.HeadingCode, &p.Status.Beam.PowerCode); err != nil {
+			return err
+		}
+	}
+	if m := j.Message; m != nil {
+		p.Message = &Message{m.Addressee, m.Text, m.ID}
+	}
+	if q := j.Query; q != nil {
+		p.Query = &Query{Type: q.Type, Directed: q.Directed, Target: q.Target}
+		if f := q.Footprint; f != nil {
+			p.Query.Footprint = &Footprint{f.Latitude, f.Longitude, f.Radius}
+		}
+	}
+	if f := j.Fix; f != nil {
+		p.Fix = &GPSFix{f.Sentence, f.Valid, f.Quality, f.Satellites}
+	}
+	if w := j.Weather; w != nil {
+		p.Weather = &Weather{w.Temperature, w.Humidity, w.Pressure, w.RainTotal, w.RainToday}
+	}
+	if d := j.DF; d != nil {
+		p.DF = &DFBearing{Bearing: d.Bearing}
+		if err := splitCodes(d.NRQ, &p.DF.NumberCode, &p.DF.RangeCode, &p.DF.QualityCode); err != nil {
+			return err
+		}
+	}
+	if a := j.Area; a != nil {
+		p.Area = &Area{Type: a.Type, LatitudeOffset: a.LatitudeOffset, LongitudeOffset: a.LongitudeOffset, LineWidth: a.LineWidth}
+		if err := splitCodes(a.Color, &p.Area.Color); err != nil {
+			return err
+		}
+	}
+	if o := j.Object; o != nil {
+		p.Object = &Object{o.Name, o.Killed, o.Item}
+	}
+	for _, d := range j.Diagnostics {
+		diag := &ParseError{Offset: d.Offset, Field: d.Field, DataType: p.Payload.Type()}
+		if d.Error != "" {
+			diag.Err = errors.New(d.Error)
+		}
+		switch d.Severity {
+		case "fatal":
+			diag.Severity = SeverityFatal
+		case "warning":
+			diag.Severity = SeverityWarning
+		default:
+			return errors.New("aprs: invalid severity " + d.Severity)
+		}
+		p.Diagnostics = append(p.Diagnostics, diag)
+	}
+	if p.Raw == "" && p.Src != nil && p.Dst != nil {
+		p.Raw = p.String()
+	}
+	return nil
+}
+
+// MarshalJSON encodes the address as a string, such as "N0CALL-9*".
+func (a Address) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON decodes an address string.
+func (a *Address) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := ParseAddress(s)
+	if err != nil {
+		return err
+	}
+	*a = *v
+	return nil
+}
+
+// MarshalJSON encodes the path as an array of address strings.
+func (p Path) MarshalJSON() ([]byte, error) {
+	var s = make([]string, len(p))
+	for i, a := range p {
+		s[i] = a.String()
+	}
+	return json.Marshal(s)
+}
+
+// UnmarshalJSON decodes an array of address strings.
+func (p *Path) UnmarshalJSON(b []byte) error {
+	var s []string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	var path = make(Path, len(s))
+	for i, a := range s {
+		var err error
+		if path[i], err = ParseAddress(a); err != nil {
+			return err
+		}
+	}
+	*p = path
+	return nil
+}
+
+// MarshalJSON encodes the symbol as a two character string of the table and
+// code, such as "/>", or an empty string if no symbol is set.
+func (s Symbol) MarshalJSON() ([]byte, error) {
+	if s == (Symbol{}) {
+		return []byte(`""`), nil
+	}
+	return json.Marshal(string(s[:]))
+}
+
+// UnmarshalJSON decodes a symbol string.
+func (s *Symbol) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch len(v) {
+	case 0:
+		*s = Symbol{}
+	case 2:
+		*s = Symbol{v[0], v[1]}
+	default:
+		return errors.New("aprs: invalid symbol " + v)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the data type identifier as a one character string.
+// Bytes above 0x7f are encoded as the ISO-8859-1 character.
+func (t DataType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(rune(t)))
+}
+
+// UnmarshalJSON decodes a data type identifier string.
+func (t *DataType) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	r, n := utf8.DecodeRuneInString(v)
+	if n == 0 || n != len(v) || r > 0xff {
+		return errors.New("aprs: invalid data type " + v)
+	}
+	*t = DataType(r)
+	return nil
+}
+
+type jsonPosition struct {
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	Ambiguity  int     `json:"ambiguity,omitempty"`
+	Symbol     *Symbol `json:"symbol,omitempty"`
+	Compressed bool    `json:"compressed,omitempty"`
+	Datum      string  `json:"datum,omitempty"`
+	Precision  float64 `json:"precision,omitempty"` // Meters
+}
+
+// MarshalJSON encodes the position, see Packet.MarshalJSON for the schema.
+func (pos Position) MarshalJSON() ([]byte, error) {
+	j := jsonPosition{
+		Latitude:   pos.Latitude,
+		Longitude:  pos.Longitude,
+		Ambiguity:  pos.Ambiguity,
+		Compressed: pos.Compressed,
+		Datum:      codes(pos.Datum),
+		Precision:  pos.Precision,
+	}
+	if pos.Symbol != (Symbol{}) {
+		j.Symbol = &pos.Symbol
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes the position.
+func (pos *Position) UnmarshalJSON(b []byte) error {
+	var j jsonPosition
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*pos = Position{
+		Latitude:   j.Latitude,
+		Longitude:  j.Longitude,
+		Ambiguity:  j.Ambiguity,
+		Compressed: j.Compressed,
+		Precision:  j.Precision,
+	}
+	if j.Symbol != nil {
+		pos.Symbol = *j.Symbol
+	}
+	return splitCodes(j.Datum, &pos.Datum)
+}
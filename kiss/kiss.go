@@ -0,0 +1,110 @@
+// Package kiss encodes APRS packets as AX.25 UI frames for KISS TNCs.
+package kiss
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+var (
+	// ErrAddress signals an address that can not be sent over AX.25.
+	ErrAddress = errors.New("kiss: invalid AX.25 address")
+)
+
+// KISS special characters.
+const (
+	FEND  = 0xc0
+	FESC  = 0xdb
+	TFEND = 0xdc
+	TFESC = 0xdd
+)
+
+// AX.25 UI frame fields.
+const (
+	control = 0x03 // UI frame
+	pid     = 0xf0 // No layer 3 protocol
+)
+
+// maxDigipeaters is the maximum number of digipeaters in an AX.25 path.
+const maxDigipeaters = 8
+
+// EncodeAX25 encodes the packet as an AX.25 UI frame, without FCS.
+func EncodeAX25(p aprs.Packet) ([]byte, error) {
+	if p.Src == nil || p.Dst == nil || len(p.Path) > maxDigipeaters {
+		return nil, ErrAddress
+	}
+
+	var b []byte
+	for i, a := range append(aprs.Path{p.Dst, p.Src}, p.Path...) {
+		last := i == len(p.Path)+1
+		e, err := encodeAddress(a, i == 0, last)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, e...)
+	}
+	b = append(b, control, pid)
+	return append(b, p.Payload...), nil
+}
+
+func encodeAddress(a *aprs.Address, command, last bool) ([]byte, error) {
+	if a == nil || len(a.Call) == 0 || len(a.Call) > 6 || a.SSID < 0 || a.SSID > 15 {
+		return nil, ErrAddress
+	}
+
+	var b = make([]byte, 7)
+	for i := 0; i < 6; i++ {
+		c := byte(' ')
+		if i < len(a.Call) {
+			c = a.Call[i]
+		}
+		b[i] = c << 1
+	}
+	b[6] = 0x60 | byte(a.SSID)<<1
+	if command || a.Repeated {
+		// C bit on the destination, H bit on digipeaters
+		b[6] |= 0x80
+	}
+	if last {
+		b[6] |= 0x01
+	}
+	return b, nil
+}
+
+// Frame wraps an AX.25 frame in a KISS data frame for TNC port 0.
+func Frame(ax25 []byte) []byte {
+	var b = []byte{FEND, 0x00}
+	for _, c := range ax25 {
+		switch c {
+		case FEND:
+			b = append(b, FESC, TFEND)
+		case FESC:
+			b = append(b, FESC, TFESC)
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, FEND)
+}
+
+// Encoder writes packets to a KISS TNC.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns an encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the packet as a KISS frame.
+func (e *Encoder) Encode(p aprs.Packet) error {
+	ax25, err := EncodeAX25(p)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(Frame(ax25))
+	return err
+}
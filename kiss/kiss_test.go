@@ -0,0 +1,123 @@
+package kiss
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+func TestEncodeAX25(t *testing.T) {
+	p, err := aprs.ParsePacket("N0CALL-10>APRS,WIDE1-1*,WIDE2-1:>Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncodeAX25(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []byte{
+		// APRS, C bit set
+		0x82, 0xa0, 0xa4, 0xa6, 0x40, 0x40, 0xe0,
+		// N0CALL-10
+		0x9c, 0x60, 0x86, 0x82, 0x98, 0x98, 0x74,
+		// WIDE1-1*, H bit set
+		0xae, 0x92, 0x88, 0x8a, 0x62, 0x40, 0xe2,
+		// WIDE2-1, last address
+		0xae, 0x92, 0x88, 0x8a, 0x64, 0x40, 0x63,
+		control, pid,
+		'>', 'T', 'e', 's', 't',
+	}
+	if !bytes.Equal(b, want) {
+		t.Errorf("expected\n% x\ngot\n% x", want, b)
+	}
+}
+
+func TestEncodeAX25Last(t *testing.T) {
+	p, err := aprs.ParsePacket("N0CALL-15>APRS:>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := EncodeAX25(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 14+3 {
+		t.Fatalf("expected 17 bytes, got % x", b)
+	}
+	if b[6] != 0xe0 {
+		t.Errorf("destination: expected SSID byte e0, got %02x", b[6])
+	}
+	if b[13] != 0x7f {
+		t.Errorf("source: expected SSID byte 7f, got %02x", b[13])
+	}
+}
+
+func TestEncodeAX25Invalid(t *testing.T) {
+	var (
+		call = &aprs.Address{Call: "N0CALL"}
+		path = make(aprs.Path, maxDigipeaters+1)
+	)
+	for i := range path {
+		path[i] = &aprs.Address{Call: "WIDE1", SSID: 1}
+	}
+
+	for _, test := range []struct {
+		Name   string
+		Packet aprs.Packet
+	}{
+		{"no source", aprs.Packet{Dst: call}},
+		{"no destination", aprs.Packet{Src: call}},
+		{"long call", aprs.Packet{Src: &aprs.Address{Call: "N0CALLS"}, Dst: call}},
+		{"empty call", aprs.Packet{Src: &aprs.Address{}, Dst: call}},
+		{"SSID", aprs.Packet{Src: &aprs.Address{Call: "N0CALL", SSID: 16}, Dst: call}},
+		{"path", aprs.Packet{Src: call, Dst: call, Path: path}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			if _, err := EncodeAX25(test.Packet); err != ErrAddress {
+				t.Errorf("expected %v, got %v", ErrAddress, err)
+			}
+		})
+	}
+}
+
+func TestFrame(t *testing.T) {
+	for _, test := range []struct {
+		Name string
+		Test []byte
+		Want []byte
+	}{
+		{"empty", nil, []byte{FEND, 0x00, FEND}},
+		{"plain", []byte("APRS"), []byte{FEND, 0x00, 'A', 'P', 'R', 'S', FEND}},
+		{"FEND", []byte{'A', FEND, 'B'}, []byte{FEND, 0x00, 'A', FESC, TFEND, 'B', FEND}},
+		{"FESC", []byte{'A', FESC, 'B'}, []byte{FEND, 0x00, 'A', FESC, TFESC, 'B', FEND}},
+		{"both", []byte{FESC, FEND}, []byte{FEND, 0x00, FESC, TFESC, FESC, TFEND, FEND}},
+		{"TFEND", []byte{TFEND, TFESC}, []byte{FEND, 0x00, TFEND, TFESC, FEND}},
+	} {
+		t.Run(test.Name, func(t *testing.T) {
+			if b := Frame(test.Test); !bytes.Equal(b, test.Want) {
+				t.Errorf("expected % x, got % x", test.Want, b)
+			}
+		})
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	p, err := aprs.ParsePacket("N0CALL>APRS:>Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ax25, err := EncodeAX25(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err = NewEncoder(&b).Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	if want := Frame(ax25); !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("expected % x, got % x", want, b.Bytes())
+	}
+}
@@ -0,0 +1,391 @@
+// Package logfile reads packet logs written by other APRS software.
+//
+// Supported are Direwolf's monitor output, where received lines are
+// prefixed by the channel such as "[0.3] ", Direwolf's CSV log, and raw logs
+// where lines are prefixed by the receive time, as a date and time or as
+// seconds since the epoch, and optionally the interface they were received
+// on.
+//
+// The timestamped format is generic, it is not modelled on the logs of a
+// particular program. Raw logs of programs such as aprsc, javAPRSSrvr or
+// APRSIS32 are read if their lines use one of the time stamp layouts in
+// timeLayouts, or seconds since the epoch, followed by the packet.
+package logfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+var (
+	// ErrUnknownFormat signals a log of which the format can not be detected.
+	ErrUnknownFormat = errors.New("logfile: unknown format")
+)
+
+// Format is a log file format.
+type Format int
+
+// Log file formats.
+const (
+	Auto        Format = iota // Detect from the first recognised line
+	Direwolf                  // [0.3] N0CALL>APRS:...
+	DirewolfCSV               // chan,utime,isotime,source,heard,level,error,dti,name,symbol,...
+	Timestamped               // 2020-03-01 09:30:00 N0CALL>APRS:...
+)
+
+// Entry is a logged packet.
+type Entry struct {
+	Time      time.Time // Receive time, zero if not logged
+	Interface string    // Channel or interface, if logged
+	Raw       string    // Raw line in TNC2 format, empty for CSV logs
+	Packet    aprs.Packet
+	Err       error // Parse error of the packet
+}
+
+// maxDetectLines is the number of lines that are tried to detect the format.
+const maxDetectLines = 100
+
+// Reader reads entries from a log.
+type Reader struct {
+	format  Format
+	scanner *bufio.Scanner
+	columns map[string]int
+	line    int
+}
+
+// NewReader returns a reader for a log in the format.
+func NewReader(r io.Reader, format Format) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<20)
+	return &Reader{format: format, scanner: s}
+}
+
+// Line returns the line number of the last entry read.
+func (r *Reader) Line() int { return r.line }
+
+// Read returns the next entry, or io.EOF at the end of the log. Lines that
+// do not hold a packet, such as comments and Direwolf's informational
+// output, are skipped. An entry with Err set is returned for packets that
+// fail to parse; the reader can continue after those.
+func (r *Reader) Read() (Entry, error) {
+	for {
+		line, err := r.next()
+		if err == io.EOF && r.format == Auto && r.line > 0 {
+			return Entry{}, ErrUnknownFormat
+		} else if err != nil {
+			return Entry{}, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if r.format == Auto {
+			// Logs often start with a banner, give up after a while.
+			if r.format = Detect(line); r.format == Auto {
+				if r.line >= maxDetectLines {
+					return Entry{}, ErrUnknownFormat
+				}
+				continue
+			}
+		}
+
+		var (
+			e  Entry
+			ok bool
+		)
+		switch r.format {
+		case Direwolf:
+			e, ok = parseDirewolf(line)
+		case DirewolfCSV:
+			if r.columns == nil {
+				r.parseHeader(line)
+				continue
+			}
+			e, ok, err = r.parseCSV(line)
+			if err != nil {
+				return Entry{}, err
+			}
+		case Timestamped:
+			e, ok = parseTimestamped(line)
+		}
+		if ok {
+			return e, nil
+		}
+	}
+}
+
+func (r *Reader) next() (string, error) {
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	r.line++
+	return strings.TrimRight(r.scanner.Text(), "\r"), nil
+}
+
+// Detect returns the format of a log line, or Auto if it is not recognised.
+func Detect(line string) Format {
+	switch {
+	case strings.HasPrefix(line, "chan,utime,"):
+		return DirewolfCSV
+	case strings.HasPrefix(line, "["):
+		if _, ok := parseDirewolf(line); ok {
+			return Direwolf
+		}
+	}
+	if _, rest, ok := parseTime(line); ok && rest != "" {
+		return Timestamped
+	}
+	return Auto
+}
+
+func newEntry(t time.Time, iface, raw string) Entry {
+	e := Entry{Time: t, Interface: iface, Raw: raw}
+	e.Packet, e.Err = aprs.ParsePacket(raw)
+	return e
+}
+
+// parseDirewolf parses a received line of Direwolf's output, such as
+// "[0.3] N0CALL>APRS:...", "[1] ..." or "[ig] ...". Transmitted lines, such as
+// "[0L] ...", are skipped.
+func parseDirewolf(line string) (Entry, bool) {
+	if !strings.HasPrefix(line, "[") {
+		return Entry{}, false
+	}
+	i := strings.Index(line, "] ")
+	if i < 2 {
+		return Entry{}, false
+	}
+	var (
+		channel = line[1:i]
+		raw     = unescapeDirewolf(line[i+2:])
+	)
+	if strings.HasSuffix(channel, "L") || strings.HasSuffix(channel, "H") {
+		return Entry{}, false
+	}
+	if channel != "ig" {
+		if j := strings.IndexByte(channel, '.'); j > 0 {
+			channel = channel[:j]
+		}
+		if _, err := strconv.Atoi(channel); err != nil {
+			return Entry{}, false
+		}
+	}
+	if !strings.Contains(raw, ">") || !strings.Contains(raw, ":") {
+		return Entry{}, false
+	}
+	return newEntry(time.Time{}, channel, raw), true
+}
+
+// unescapeDirewolf replaces the "<0x0d>" notation of unprintable bytes.
+func unescapeDirewolf(s string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "<0x")
+		if i < 0 || len(s) < i+6 || s[i+5] != '>' {
+			b.WriteString(s)
+			return strings.TrimRight(b.String(), "\r\n")
+		}
+		c, err := strconv.ParseUint(s[i+3:i+5], 16, 8)
+		if err != nil {
+			b.WriteString(s[:i+3])
+			s = s[i+3:]
+			continue
+		}
+		b.WriteString(s[:i])
+		b.WriteByte(byte(c))
+		s = s[i+6:]
+	}
+}
+
+// Time stamp layouts of timestamped logs, tried in order.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+}
+
+// parseTime parses the time stamp at the start of a line, which is either a
+// date and time or fractional seconds since the epoch, optionally between
+// brackets. It returns the remainder of the line.
+func parseTime(line string) (time.Time, string, bool) {
+	var bracket bool
+	if bracket = strings.HasPrefix(line, "["); bracket {
+		i := strings.IndexByte(line, ']')
+		if i < 0 {
+			return time.Time{}, "", false
+		}
+		t, _, ok := parseTime(line[1:i] + " ")
+		return t, strings.TrimLeft(line[i+1:], " \t"), ok
+	}
+
+	f := strings.SplitN(line, " ", 3)
+	if len(f) < 2 {
+		return time.Time{}, "", false
+	}
+
+	// Seconds since the epoch.
+	if len(f[0]) >= 9 && strings.Trim(f[0], "0123456789.") == "" {
+		if s, err := strconv.ParseFloat(f[0], 64); err == nil {
+			sec := int64(s)
+			t := time.Unix(sec, int64((s-float64(sec))*1e9)).UTC().Round(time.Millisecond)
+			return t, strings.TrimLeft(line[len(f[0]):], " \t"), true
+		}
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, f[0]); err == nil {
+			return t, strings.TrimLeft(line[len(f[0]):], " \t"), true
+		}
+		if len(f) == 3 || f[1] != "" {
+			s := f[0] + " " + f[1]
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, strings.TrimLeft(line[len(s):], " \t"), true
+			}
+		}
+	}
+	return time.Time{}, "", false
+}
+
+// parseTimestamped parses a line with a time stamp, followed by an optional
+// interface, such as "[APRS-IS]" or "port14580:", and the raw packet.
+func parseTimestamped(line string) (Entry, bool) {
+	t, rest, ok := parseTime(line)
+	if !ok {
+		return Entry{}, false
+	}
+
+	var iface string
+	if i := strings.IndexAny(rest, " \t"); i > 0 {
+		token := rest[:i]
+		switch {
+		case strings.HasPrefix(token, "[") && strings.HasSuffix(token, "]"):
+			iface, rest = token[1:len(token)-1], rest[i+1:]
+		case strings.HasSuffix(token, ":") && !strings.Contains(token, ">"):
+			iface, rest = token[:len(token)-1], rest[i+1:]
+		}
+	}
+
+	rest = strings.TrimLeft(rest, " \t")
+	if !strings.Contains(rest, ">") || !strings.Contains(rest, ":") {
+		return Entry{}, false
+	}
+	return newEntry(t, iface, rest), true
+}
+
+func (r *Reader) parseHeader(line string) {
+	r.columns = make(map[string]int)
+	for i, name := range strings.Split(line, ",") {
+		r.columns[strings.TrimSpace(name)] = i
+	}
+}
+
+// parseCSV parses a line of Direwolf's CSV log. These logs hold decoded
+// fields instead of the raw packet, the packet is rebuilt from the source,
+// position, symbol, speed (knots), course, altitude (meters), status and
+// comment. The destination is set to APRS and the payload is rebuilt as a
+// position or status report, so the packet can be formatted, but it is not
+// the payload that was received.
+func (r *Reader) parseCSV(line string) (Entry, bool, error) {
+	if strings.HasPrefix(line, "chan,utime,") {
+		// Header repeated, logs are often concatenated.
+		r.parseHeader(line)
+		return Entry{}, false, nil
+	}
+
+	rec, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		if perr, ok := err.(*csv.ParseError); ok {
+			perr.StartLine, perr.Line = r.line, r.line
+		}
+		return Entry{Err: err}, true, nil
+	}
+	field := func(name string) string {
+		if i, ok := r.columns[name]; ok && i < len(rec) {
+			return rec[i]
+		}
+		return ""
+	}
+
+	var e Entry
+	e.Interface = field("chan")
+	if s, err := strconv.ParseFloat(field("utime"), 64); err == nil {
+		e.Time = time.Unix(int64(s), 0).UTC()
+	}
+
+	p := &e.Packet
+	p.Dst = &aprs.Address{Call: "APRS"}
+	if p.Src, err = aprs.ParseAddress(field("source")); err != nil || p.Src.Call == "" {
+		p.Src = nil
+		e.Err = aprs.ErrAddressInvalid
+		return e, true, nil
+	}
+	if heard := field("heard"); heard != "" && heard != field("source") {
+		if a, err := aprs.ParseAddress(heard); err == nil {
+			a.Repeated = true
+			p.Path = aprs.Path{a}
+		}
+	}
+	if s := field("symbol"); len(s) == 2 {
+		p.Symbol = aprs.Symbol{s[0], s[1]}
+	}
+	lat, errLat := strconv.ParseFloat(field("latitude"), 64)
+	lng, errLng := strconv.ParseFloat(field("longitude"), 64)
+	if errLat == nil && errLng == nil {
+		p.Position = &aprs.Position{Latitude: lat, Longitude: lng, Symbol: p.Symbol}
+	}
+	if v, err := strconv.ParseFloat(field("speed"), 64); err == nil {
+		p.Velocity.Speed = aprs.Speed(v) * aprs.Knot
+	}
+	if v, err := strconv.ParseFloat(field("course"), 64); err == nil {
+		p.Velocity.Course = v
+	}
+	if v, err := strconv.ParseFloat(field("altitude"), 64); err == nil {
+		p.Altitude = aprs.Altitude(v)
+	}
+	p.Comment = field("comment")
+	if s := field("status"); s != "" {
+		p.Status = &aprs.Status{Text: s}
+	}
+	if name := field("name"); name != "" && name != field("source") {
+		p.Object = &aprs.Object{Name: name}
+	}
+	p.Payload = csvPayload(e)
+	return e, true, nil
+}
+
+// csvPayload rebuilds the payload of a CSV entry.
+func csvPayload(e Entry) aprs.Payload {
+	p := e.Packet
+	switch {
+	case p.Position != nil:
+		r := aprs.PositionReport{Position: *p.Position, Comment: p.Comment}
+		if p.Velocity != (aprs.Velocity{}) {
+			r.Velocity = &p.Velocity
+		}
+		if p.Altitude != 0 {
+			r.Altitude = &p.Altitude
+		}
+		if o := p.Object; o != nil && len(o.Name) <= 9 {
+			r.Object = o
+			if !e.Time.IsZero() {
+				r.Time = &e.Time
+			}
+		}
+		return aprs.Payload(r.String())
+	case p.Status != nil:
+		return aprs.Payload(p.Status.String())
+	default:
+		return aprs.Payload(">" + p.Comment)
+	}
+}
@@ -0,0 +1,174 @@
+package logfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pd0mz/go-aprs"
+)
+
+type testEntry struct {
+	Time      string
+	Interface string
+	Raw       string
+	Err       bool
+}
+
+func testRead(t *testing.T, log string, format Format, want []testEntry) []Entry {
+	t.Helper()
+	var (
+		r       = NewReader(strings.NewReader(log), format)
+		entries []Entry
+	)
+	for {
+		e, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("line %d: %v", r.Line(), err)
+		}
+		entries = append(entries, e)
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		var ts string
+		if !e.Time.IsZero() {
+			ts = e.Time.Format(time.RFC3339Nano)
+		}
+		if ts != w.Time || e.Interface != w.Interface || e.Raw != w.Raw || (e.Err != nil) != w.Err {
+			t.Errorf("entry %d: expected %+v, got time %q, interface %q, raw %q, error %v",
+				i, w, ts, e.Interface, e.Raw, e.Err)
+		}
+	}
+	return entries
+}
+
+const testDirewolf = `Dire Wolf version 1.6
+Audio device for both receive and transmit: default  (channel 0)
+
+N0CALL-9 audio level = 62(19/12)   [NONE]   |||||||__
+[0.3] N0CALL-9>APDW16,WIDE1-1,WIDE2-1:!4903.50N/07201.75W>Test<0x0d>
+Position, Car, DireWolf
+N 49 03.5000, W 072 01.7500
+[0L] N0CALL>APDW16,WIDE2-1:!5206.00N/00506.00E-
+[ig] N0CALL-5>APRS,TCPIP*,qAC,T2TEST:>status
+[1] N0CALL-7>APRS:!invalid
+`
+
+func TestDirewolf(t *testing.T) {
+	for _, format := range []Format{Auto, Direwolf} {
+		entries := testRead(t, testDirewolf, format, []testEntry{
+			{Interface: "0", Raw: "N0CALL-9>APDW16,WIDE1-1,WIDE2-1:!4903.50N/07201.75W>Test"},
+			{Interface: "ig", Raw: "N0CALL-5>APRS,TCPIP*,qAC,T2TEST:>status"},
+			{Interface: "1", Raw: "N0CALL-7>APRS:!invalid", Err: true},
+		})
+		if p := entries[0].Packet; p.Comment != "Test" || p.Position == nil {
+			t.Errorf("unexpected packet %+v", p)
+		}
+	}
+}
+
+const testTimestamped = `# aprsc 2.1.4
+2020-03-01 09:30:00 N0CALL>APRS:>one
+2020-03-01T09:30:01Z [APRS-IS] N0CALL>APRS:>two
+1583055002.5 port14580: N0CALL>APRS:>three
+[2020/03/01 09:30:03] N0CALL>APRS:>four
+2020-03-01 09:30:04 server restarted
+2020-03-01 09:30:05.250 N0CALL>APRS:!invalid
+`
+
+func TestTimestamped(t *testing.T) {
+	for _, format := range []Format{Auto, Timestamped} {
+		testRead(t, testTimestamped, format, []testEntry{
+			{Time: "2020-03-01T09:30:00Z", Raw: "N0CALL>APRS:>one"},
+			{Time: "2020-03-01T09:30:01Z", Interface: "APRS-IS", Raw: "N0CALL>APRS:>two"},
+			{Time: "2020-03-01T09:30:02.5Z", Interface: "port14580", Raw: "N0CALL>APRS:>three"},
+			{Time: "2020-03-01T09:30:03Z", Raw: "N0CALL>APRS:>four"},
+			{Time: "2020-03-01T09:30:05.25Z", Raw: "N0CALL>APRS:!invalid", Err: true},
+		})
+	}
+}
+
+const testCSV = `chan,utime,isotime,source,heard,level,error,dti,name,symbol,latitude,longitude,speed,course,altitude,frequency,offset,tone,system,status,telemetry,comment
+0,1583055000,2020-03-01T09:30:00Z,N0CALL-9,WIDE1-1,62(19/12),0,!,N0CALL-9,/>,49.058333,-72.029167,36,88,376,,,,,,,Test
+0,1583055060,2020-03-01T09:31:00Z,N0CALL,N0CALL,50(10/10),0,;,EVENT,/E,52.200000,5.200000,0,0,,,,,,,,Meeting
+chan,utime,isotime,source,heard,level,error,dti,name,symbol,latitude,longitude,speed,course,altitude,frequency,offset,tone,system,status,telemetry,comment
+1,1583055120,2020-03-01T09:32:00Z,N0CALL-5,N0CALL-5,48(9/8),0,>,N0CALL-5,,,,,,,,,,,On the air,,
+1,1583055180,2020-03-01T09:33:00Z,,N0CALL-5,48(9/8),0,>,,,,,,,,,,,,,,
+1,1583055240,2020-03-01T09:34:00Z,N0CALL-5,N0CALL-5,48(9/8),0,>,N0CALL-5,,,,,,,,,,,"unterminated,,
+`
+
+func TestDirewolfCSV(t *testing.T) {
+	for _, format := range []Format{Auto, DirewolfCSV} {
+		entries := testRead(t, testCSV, format, []testEntry{
+			{Time: "2020-03-01T09:30:00Z", Interface: "0"},
+			{Time: "2020-03-01T09:31:00Z", Interface: "0"},
+			{Time: "2020-03-01T09:32:00Z", Interface: "1"},
+			{Time: "2020-03-01T09:33:00Z", Interface: "1", Err: true},
+			{Err: true},
+		})
+
+		var perr *csv.ParseError
+		if !errors.As(entries[4].Err, &perr) || perr.Line != 7 {
+			t.Errorf("expected a CSV error on line 7, got %v", entries[4].Err)
+		}
+
+		p := entries[0].Packet
+		if s := p.Velocity.Speed.Knots(); s < 35.99 || s > 36.01 {
+			t.Errorf("expected 36 knots, got %f", s)
+		}
+		if p.Velocity.Course != 88 {
+			t.Errorf("expected course 88, got %f", p.Velocity.Course)
+		}
+		if p.Altitude != 376 {
+			t.Errorf("expected 376 m, got %f", float64(p.Altitude))
+		}
+
+		for i, want := range []string{
+			"N0CALL-9>APRS,WIDE1-1*:!4903.50N/07201.75W>088/036/A=001234Test",
+			"N0CALL>APRS:;EVENT    *010931z5212.00N/00512.00EEMeeting",
+			"N0CALL-5>APRS:>On the air",
+		} {
+			if s := entries[i].Packet.String(); s != want {
+				t.Errorf("entry %d: expected %q, got %q", i, want, s)
+			}
+			if _, err := aprs.ParsePacket(entries[i].Packet.String()); err != nil {
+				t.Errorf("entry %d: %v", i, err)
+			}
+		}
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	r := NewReader(strings.NewReader("hello\nworld\n"), Auto)
+	if _, err := r.Read(); err != ErrUnknownFormat {
+		t.Errorf("expected %v, got %v", ErrUnknownFormat, err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, test := range []struct {
+		Line string
+		Want Format
+	}{
+		{"[0.3] N0CALL>APRS:>test", Direwolf},
+		{"[0L] N0CALL>APRS:>test", Auto},
+		{"chan,utime,isotime,source", DirewolfCSV},
+		{"2020-03-01 09:30:00 N0CALL>APRS:>test", Timestamped},
+		{"[2020-03-01 09:30:00] N0CALL>APRS:>test", Timestamped},
+		{"1583055000 N0CALL>APRS:>test", Timestamped},
+		{"N0CALL>APRS:>test", Auto},
+	} {
+		if f := Detect(test.Line); f != test.Want {
+			t.Errorf("%q: expected format %d, got %d", test.Line, test.Want, f)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package aprs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidMessage signals a corrupted APRS message.
+	ErrInvalidMessage = errors.New("aprs: invalid message")
+)
+
+// Message is a decoded message, bulletin or announcement.
+type Message struct {
+	Addressee string
+	Text      string
+	ID        string // Message number, if any
+}
+
+// String formats the message as an APRS payload, including the leading ':'.
+func (m Message) String() string {
+	if m.ID != "" {
+		return fmt.Sprintf(":%-9s:%s{%s", m.Addressee, m.Text, m.ID)
+	}
+	return fmt.Sprintf(":%-9s:%s", m.Addressee, m.Text)
+}
+
+// ParseMessage parses a message (without the leading ':').
+func ParseMessage(s string) (Message, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 14, page 71 (81 in PDF)
+
+	m := Message{}
+
+	if len(s) < 10 || s[9] != ':' {
+		return m, ErrInvalidMessage
+	}
+
+	m.Addressee = strings.TrimSpace(s[:9])
+	m.Text = s[10:]
+	if i := strings.LastIndexByte(m.Text, '{'); i >= 0 {
+		m.ID = m.Text[i+1:]
+		m.Text = m.Text[:i]
+	}
+
+	return m, nil
+}
@@ -0,0 +1,239 @@
+package aprs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNMEAChecksum signals a NMEA sentence with a bad checksum.
+	ErrNMEAChecksum = errors.New("aprs: invalid NMEA checksum")
+
+	// ErrInvalidNMEA signals a corrupted or unsupported NMEA sentence.
+	ErrInvalidNMEA = errors.New("aprs: invalid NMEA sentence")
+)
+
+// GPSFix describes the GPS fix reported in a raw NMEA sentence.
+type GPSFix struct {
+	Sentence   string // Sentence type, such as "GPRMC"
+	Valid      bool   // Fix is valid
+	Quality    int    // GGA fix quality, 0 = invalid, 1 = GPS, 2 = DGPS
+	Satellites int    // GGA number of satellites in use
+}
+
+// NMEA is a decoded raw NMEA sentence.
+type NMEA struct {
+	Fix      GPSFix
+	Position *Position
+	Time     *time.Time
+	Velocity Velocity
+	Altitude Altitude
+	Name     string // Waypoint name
+}
+
+// ParseNMEA parses a raw NMEA sentence, including the leading '$'.
+func ParseNMEA(s string) (NMEA, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 6, page 19 (29 in PDF)
+
+	n := NMEA{}
+
+	if len(s) < 7 || s[0] != '$' {
+		return n, ErrInvalidNMEA
+	}
+
+	s = strings.TrimRight(s, "\r\n ")
+	if i := strings.LastIndexByte(s, '*'); i >= 0 {
+		c, err := strconv.ParseUint(s[i+1:], 16, 8)
+		if err != nil {
+			return n, ErrNMEAChecksum
+		}
+		if byte(c) != nmeaChecksum(s[1:i]) {
+			return n, ErrNMEAChecksum
+		}
+		s = s[:i]
+	}
+
+	f := strings.Split(s[1:], ",")
+	n.Fix.Sentence = f[0]
+	if len(f[0]) != 5 {
+		return n, ErrInvalidNMEA
+	}
+
+	var err error
+	switch f[0][2:] {
+	case "RMC":
+		// $GPRMC,hhmmss.ss,A,llll.ll,a,yyyyy.yy,a,x.x,x.x,ddmmyy,x.x,a
+		if len(f) < 10 {
+			return n, ErrInvalidNMEA
+		}
+		n.Fix.Valid = f[2] == "A"
+		if n.Position, err = parseNMEAPosition(f[3], f[4], f[5], f[6]); err != nil {
+			return n, err
+		}
+		if f[1] != "" && f[9] != "" {
+			if n.Time, err = parseNMEATime(f[9] + f[1]); err != nil {
+				return n, err
+			}
+		}
+		if f[7] != "" {
+			var kn float64
+			if kn, err = strconv.ParseFloat(f[7], 64); err != nil {
+				return n, err
+			}
+			n.Velocity.Speed = Speed(kn) * Knot
+		}
+		if f[8] != "" {
+			if n.Velocity.Course, err = strconv.ParseFloat(f[8], 64); err != nil {
+				return n, err
+			}
+		}
+
+	case "GGA":
+		// $GPGGA,hhmmss.ss,llll.ll,a,yyyyy.yy,a,x,xx,x.x,x.x,M,x.x,M,x.x,xxxx
+		if len(f) < 11 {
+			return n, ErrInvalidNMEA
+		}
+		if n.Fix.Quality, err = strconv.Atoi(f[6]); err != nil {
+			return n, err
+		}
+		n.Fix.Valid = n.Fix.Quality > 0
+		if f[7] != "" {
+			if n.Fix.Satellites, err = strconv.Atoi(f[7]); err != nil {
+				return n, err
+			}
+		}
+		if n.Position, err = parseNMEAPosition(f[2], f[3], f[4], f[5]); err != nil {
+			return n, err
+		}
+		if f[1] != "" {
+			if n.Time, err = parseNMEATime(f[1]); err != nil {
+				return n, err
+			}
+		}
+		if f[9] != "" {
+			var m float64
+			if m, err = strconv.ParseFloat(f[9], 64); err != nil {
+				return n, err
+			}
+			n.Altitude = Altitude(m)
+		}
+
+	case "GLL":
+		// $GPGLL,llll.ll,a,yyyyy.yy,a,hhmmss.ss,A
+		if len(f) < 5 {
+			return n, ErrInvalidNMEA
+		}
+		n.Fix.Valid = len(f) < 7 || f[6] == "A"
+		if n.Position, err = parseNMEAPosition(f[1], f[2], f[3], f[4]); err != nil {
+			return n, err
+		}
+		if len(f) > 5 && f[5] != "" {
+			if n.Time, err = parseNMEATime(f[5]); err != nil {
+				return n, err
+			}
+		}
+
+	case "WPL":
+		// $GPWPL,llll.ll,a,yyyyy.yy,a,c--c
+		if len(f) < 6 {
+			return n, ErrInvalidNMEA
+		}
+		n.Fix.Valid = true
+		if n.Position, err = parseNMEAPosition(f[1], f[2], f[3], f[4]); err != nil {
+			return n, err
+		}
+		n.Name = f[5]
+
+	default:
+		return n, ErrInvalidNMEA
+	}
+
+	return n, nil
+}
+
+func nmeaChecksum(s string) byte {
+	var c byte
+	for i := 0; i < len(s); i++ {
+		c ^= s[i]
+	}
+	return c
+}
+
+func parseNMEAPosition(lat, latHemi, lng, lngHemi string) (*Position, error) {
+	if lat == "" || lng == "" {
+		// No fix
+		return nil, nil
+	}
+
+	var (
+		pos = &Position{}
+		err error
+	)
+	if pos.Latitude, err = parseNMEACoordinate(lat, 2); err != nil {
+		return nil, err
+	}
+	if pos.Longitude, err = parseNMEACoordinate(lng, 3); err != nil {
+		return nil, err
+	}
+	switch latHemi {
+	case "N":
+	case "S":
+		pos.Latitude = -pos.Latitude
+	default:
+		return nil, ErrInvalidPosition
+	}
+	switch lngHemi {
+	case "E":
+	case "W":
+		pos.Longitude = -pos.Longitude
+	default:
+		return nil, ErrInvalidPosition
+	}
+	if pos.Latitude < -90 || pos.Latitude > 90 || pos.Longitude < -180 || pos.Longitude > 180 {
+		return nil, ErrInvalidPosition
+	}
+	return pos, nil
+}
+
+// parseNMEACoordinate parses a (d)ddmm.mmmm coordinate.
+func parseNMEACoordinate(s string, digits int) (float64, error) {
+	if len(s) < digits+2 {
+		return 0, ErrInvalidPosition
+	}
+	d, err := strconv.ParseUint(s[:digits], 10, 8)
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.ParseFloat(s[digits:], 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(d) + m/60.0, nil
+}
+
+// parseNMEATime parses a [ddmmyy]hhmmss[.ss] time stamp.
+func parseNMEATime(s string) (*time.Time, error) {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+
+	var (
+		t   time.Time
+		err error
+	)
+	switch len(s) {
+	case 6:
+		t, err = time.Parse("150405", s)
+	case 12:
+		t, err = time.Parse("020106150405", s)
+	default:
+		err = fmt.Errorf("aprs: invalid NMEA time %q", s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -2,7 +2,6 @@ package aprs
 
 import (
 	"errors"
-	"fmt"
 	"math"
 	"strconv"
 	"strings"
@@ -37,12 +36,13 @@ func (p Payload) Len() int { return len(p) }
 
 type Velocity struct {
 	Course float64 // Degrees
-	Speed  float64 // Knots
+	Speed  Speed
 }
 
 type Wind struct {
 	Direction float64 // Degrees
-	Speed     float64 // Knots
+	Speed     Speed
+	Gust      Speed
 }
 
 type PowerHeightGain struct {
@@ -60,12 +60,8 @@ func (p PowerHeightGain) Power() int {
 	return w * w
 }
 
-func (p PowerHeightGain) Height() float64 {
-	h := float64(p.HeightCode - '0')
-	if h <= 0 {
-		return 10
-	}
-	return math.Pow(2, h) * 10
+func (p PowerHeightGain) Height() Altitude {
+	return heightCode(p.HeightCode)
 }
 
 func (p PowerHeightGain) Gain() int {
@@ -76,6 +72,11 @@ func (p PowerHeightGain) Gain() int {
 	return d
 }
 
+// Range is the radio range derived from the power, height and gain.
+func (p PowerHeightGain) Range() Distance {
+	return Distance(math.Sqrt(2*p.Height().Feet()*math.Sqrt((float64(p.Power())/10)*(float64(p.Gain())/2)))) * Mile
+}
+
 func (p PowerHeightGain) Directivity() float64 {
 	d := int(p.DirectivityCode - '0')
 	if d <= 0 {
@@ -99,12 +100,17 @@ func (o OmniDFStrength) Strength() int {
 	return w * w
 }
 
-func (o OmniDFStrength) Height() float64 {
-	h := float64(o.HeightCode - '0')
+func (o OmniDFStrength) Height() Altitude {
+	return heightCode(o.HeightCode)
+}
+
+// heightCode decodes the height above average terrain of PHG and DFS.
+func heightCode(c byte) Altitude {
+	h := float64(c - '0')
 	if h <= 0 {
-		return 10
+		return Altitude(10 * Foot)
 	}
-	return math.Pow(2, h) * 10
+	return Altitude(Distance(math.Pow(2, h)*10) * Foot)
 }
 
 func (o OmniDFStrength) Gain() int {
@@ -123,159 +129,344 @@ func (o OmniDFStrength) Directivity() float64 {
 	return float64(d%8) * 45.0
 }
 
+// DFBearing is the /BRG/NRQ direction finding extension.
+type DFBearing struct {
+	Bearing     float64 // Degrees
+	NumberCode  byte
+	RangeCode   byte
+	QualityCode byte
+}
+
+// Hits is the percentage of hits on the bearing.
+func (d DFBearing) Hits() float64 {
+	n := int(d.NumberCode - '0')
+	if n <= 0 || n > 9 {
+		return 0
+	}
+	if n == 9 {
+		return 100
+	}
+	return float64(n) / 8 * 100
+}
+
+// Range of the bearing.
+func (d DFBearing) Range() Distance {
+	r := int(d.RangeCode - '0')
+	if r < 0 || r > 9 {
+		return 0
+	}
+	return Distance(math.Pow(2, float64(r))) * Mile
+}
+
+// Accuracy of the bearing in degrees, 0 if unknown or useless.
+func (d DFBearing) Accuracy() float64 {
+	q := int(d.QualityCode - '0')
+	if q <= 0 || q > 9 {
+		return 0
+	}
+	return []float64{240, 120, 64, 32, 16, 8, 4, 2, 1}[q-1]
+}
+
+// Area is the Tyy/Cxx area object extension.
+type Area struct {
+	Type            int     // 0 = circle, 1 = line (right), 2 = ellipse, 3 = triangle, 4 = box, 5-9 = filled
+	LatitudeOffset  float64 // Degrees
+	LongitudeOffset float64 // Degrees
+	Color           byte
+	LineWidth       int // Only for lines
+}
+
 type Packet struct {
-	Raw      string
-	Src      *Address
-	Dst      *Address
-	Path     Path
-	Payload  Payload
-	Position *Position
-	Time     *time.Time
-	Altitude float64 // Feet
-	Velocity Velocity
-	Wind     Wind
-	PHG      PowerHeightGain
-	DFS      OmniDFStrength
-	Range    float64 // Miles
-	Symbol   Symbol
-	Comment  string
-	data     string // Unparsed data
+	Raw          string
+	Src          *Address
+	Dst          *Address
+	Path         Path
+	Payload      Payload
+	Position     *Position
+	Time         *time.Time
+	Altitude     Altitude
+	Velocity     Velocity
+	Wind         Wind
+	PHG          PowerHeightGain
+	DFS          OmniDFStrength
+	Range        Distance
+	Symbol       Symbol
+	Comment      string
+	Status       *Status
+	Message      *Message
+	Query        *Query
+	Capabilities Capabilities
+	ThirdParty   *Packet // Encapsulated packet of third-party traffic
+	Fix          *GPSFix
+	Weather      *Weather
+	DF           *DFBearing
+	Area         *Area
+	Signpost     string
+	Object       *Object       // Object or item name and state
+	Diagnostics  []*ParseError // Parts of the payload that were not decoded
+	data         string        // Unparsed data
+	dataOffset   int           // Offset of the unparsed data in the payload
 }
 
+// ParseMode controls how failures to decode the payload are reported.
+type ParseMode int
+
+// Parse modes.
+const (
+	// Strict returns payload decoding failures as an error.
+	Strict ParseMode = iota
+
+	// Lenient adds payload decoding failures to the packet diagnostics, only
+	// errors in the header are returned. The packet always has the header,
+	// the raw payload and the fields that could be decoded.
+	Lenient
+)
+
+// ParsePacket parses a packet in TNC2 format in Strict mode.
 func ParsePacket(raw string) (Packet, error) {
+	return ParsePacketMode(raw, Strict)
+}
+
+// ParsePacketMode parses a packet in TNC2 format in the parse mode.
+func ParsePacketMode(raw string, mode ParseMode) (Packet, error) {
 	p := Packet{Raw: raw}
 
 	var i int
 	if i = strings.Index(raw, ":"); i < 0 {
-		return p, ErrInvalidPacket
+		return p, &ParseError{Offset: len(raw), Field: "header", Err: ErrInvalidPacket}
 	}
 	p.Payload = Payload(raw[i+1:])
+	var payloadOffset = i + 1
 
 	// Parse src, dst and path
 	var err error
 	var a = raw[:i]
 	if i = strings.Index(a, ">"); i < 0 {
-		return p, ErrInvalidPacket
+		return p, &ParseError{Offset: len(a), Field: "header", Err: ErrInvalidPacket}
 	}
 	if p.Src, err = ParseAddress(a[:i]); err != nil {
-		return p, err
+		return p, &ParseError{Offset: 0, Field: "source", Err: err}
 	}
-	var r = strings.Split(a[i+1:], ",")
+	var (
+		dstOffset = i + 1
+		r         = strings.Split(a[dstOffset:], ",")
+	)
 	if p.Dst, err = ParseAddress(r[0]); err != nil {
-		return p, err
+		return p, &ParseError{Offset: dstOffset, Field: "destination", Err: err}
 	}
 	if p.Path, err = ParsePath(strings.Join(r[1:], ",")); err != nil {
-		return p, err
+		return p, &ParseError{Offset: dstOffset + len(r[0]) + 1, Field: "path", Err: err}
 	}
 
-	// Post processing of payload
-	err = p.parse()
+	// Post processing of payload, offsets are relative to the payload except
+	// for the Mic-E fields encoded in the destination.
+	var t = p.Payload.Type()
+	err = p.parse(mode)
+	for _, d := range p.Diagnostics {
+		d.DataType = t
+		d.Offset += payloadOffset
+	}
+	if err != nil {
+		perr := fieldError(err, 0, "payload").(*ParseError)
+		perr.DataType = t
+		if (t == '`' || t == '\'') && perr.Field == "destination" {
+			perr.Offset += dstOffset
+		} else {
+			perr.Offset += payloadOffset
+		}
+		if mode == Lenient {
+			p.Diagnostics = append(p.Diagnostics, perr)
+			return p, nil
+		}
+		err = perr
+	}
 	return p, err
 }
 
-func (p *Packet) parse() error {
+// String formats the packet in TNC2 monitor format. Packets without a source
+// or destination, such as packets with an invalid header, return Raw.
+func (p Packet) String() string {
+	if p.Src == nil || p.Dst == nil {
+		return p.Raw
+	}
+	var h = []string{p.Src.String(), ">", p.Dst.String()}
+	if len(p.Path) > 0 {
+		h = append(h, ",", p.Path.String())
+	}
+	return strings.Join(h, "") + ":" + string(p.Payload)
+}
+
+func (p *Packet) parse(mode ParseMode) error {
 	s := string(p.Payload)
-	//log.Printf("parse %q [%c]\n", s, p.Payload.Type())
 
 	switch p.Payload.Type() {
 	case '!': // Lat/Long Position Report Format — without Timestamp
-		var o = strings.IndexByte(s, '!')
-		pos, txt, err := ParsePosition(s[o+1:], !isDigit(s[o+1]))
-		if err != nil {
-			return err
+		if len(s) < 2 {
+			return &ParseError{Offset: len(s), Field: "position", Err: ErrInvalidPosition}
 		}
-		p.Position = &pos
-		p.data = txt
-		if len(s) >= 20 {
-			p.Symbol[0] = s[9]
-			p.Symbol[1] = s[19]
+		if err := p.parsePositionAt(s, 1, !isDigit(s[1])); err != nil {
+			return err
 		}
 	case '=':
-		compressed := IsValidCompressedSymTable(s[1])
-		pos, txt, err := ParsePosition(s[1:], compressed)
-		if err != nil {
-			return err
+		if len(s) < 2 {
+			return &ParseError{Offset: len(s), Field: "position", Err: ErrInvalidPosition}
 		}
-		p.Position = &pos
-		p.data = txt
-		if compressed {
-			p.Symbol[0] = s[1]
-			p.Symbol[1] = s[10]
-		} else {
-			p.Symbol[0] = s[9]
-			p.Symbol[1] = s[19]
+		if err := p.parsePositionAt(s, 1, IsValidCompressedSymTable(s[1])); err != nil {
+			return err
 		}
 	case '/', '@': // Lat/Long Position Report Format — with Timestamp
 		if len(s) < 8 {
-			return ErrInvalidPosition
+			return &ParseError{Offset: len(s), Field: "timestamp", Err: ErrInvalidPosition}
 		}
 
-		var compressed bool
-		if s[7] == 'h' || s[7] == 'z' || s[7] == '/' {
+		var o int
+		switch {
+		case s[7] == 'h' || s[7] == 'z' || s[7] == '/':
 			if ts, err := ParseTime(s[1:]); err == nil {
 				p.Time = &ts
+			} else {
+				p.warn(1, "timestamp", err)
 			}
-			compressed = IsValidCompressedSymTable(s[8])
-			pos, txt, err := ParsePosition(s[8:], compressed)
-			if err != nil {
-				return err
-			}
-			p.Position = &pos
-			p.data = txt
-		} else if s[7] >= '0' && s[7] <= '9' {
+			o = 8
+		case isDigit(s[7]):
 			ts, err := ParseTime(s[1:])
 			if err != nil {
-				return err
+				return fieldError(err, 1, "timestamp")
 			}
 			p.Time = &ts
-			compressed = IsValidCompressedSymTable(s[10])
-			pos, txt, err := ParsePosition(s[10:], compressed)
-			if err != nil {
-				return err
-			}
-			p.Position = &pos
-			p.data = txt
+			o = 10
+		default:
+			return &ParseError{Offset: 7, Field: "timestamp", Err: TimeFormatError{s[1:8]}}
 		}
-		if compressed {
-			p.Symbol[0] = s[8]
-			p.Symbol[1] = s[17]
-		} else {
-			p.Symbol[0] = s[16]
-			p.Symbol[1] = s[26]
+		if len(s) <= o {
+			return &ParseError{Offset: len(s), Field: "position", Err: ErrInvalidPosition}
+		}
+		if err := p.parsePositionAt(s, o, IsValidCompressedSymTable(s[o])); err != nil {
+			return err
 		}
 	case ';':
-		pos, txt, err := ParsePosition(s[18:], !isDigit(s[18]))
+		obj, ts, data, err := ParseObject(s)
 		if err != nil {
-			return err
+			return fieldError(err, 0, "object")
+		}
+		p.Object = &obj
+		p.Time = &ts
+		if err = p.parseObjectPosition(data); err != nil {
+			return fieldError(err, len(s)-len(data), "position")
+		}
+	case ')':
+		obj, data, err := ParseItem(s)
+		if err != nil {
+			return fieldError(err, 0, "item")
+		}
+		p.Object = &obj
+		if err = p.parseObjectPosition(data); err != nil {
+			return fieldError(err, len(s)-len(data), "position")
 		}
-		p.Position = &pos
-		p.data = txt
 	case '[':
 		pos, txt, err := ParsePositionGrid(s[1:])
 		if err != nil {
-			return err
+			return fieldError(err, 1, "locator")
 		}
 		p.Position = &pos
 		p.data = txt
+	case ':':
+		msg, err := ParseMessage(s[1:])
+		if err != nil {
+			return fieldError(err, 1, "message")
+		}
+		p.Message = &msg
+		if strings.HasPrefix(msg.Text, "?") {
+			if q, err := ParseDirectedQuery(msg.Text); err == nil {
+				p.Query = &q
+			}
+		}
+
+		return nil // there is no additional data to parse
+	case '?':
+		q, err := ParseQuery(s[1:])
+		if err != nil {
+			return fieldError(err, 1, "query")
+		}
+		p.Query = &q
+
+		return nil // there is no additional data to parse
+	case '<':
+		c, err := ParseCapabilities(s[1:])
+		if err != nil {
+			return fieldError(err, 1, "capabilities")
+		}
+		p.Capabilities = c
+
+		return nil // there is no additional data to parse
+	case '}':
+		tp, err := parseThirdParty(s[1:], mode)
+		if err != nil {
+			return fieldError(err, 1, "third-party")
+		}
+		p.ThirdParty = tp
+
+		return nil // there is no additional data to parse
+	case '$':
+		if strings.HasPrefix(s, "$ULTW") {
+			wx, wind, err := ParseUltimeter(s)
+			if err != nil {
+				return fieldError(err, 0, "weather")
+			}
+			p.Weather = &wx
+			p.Wind = wind
+			return nil
+		}
+
+		n, err := ParseNMEA(s)
+		if err != nil {
+			return fieldError(err, 0, "nmea")
+		}
+		p.Fix = &n.Fix
+		p.Position = n.Position
+		p.Time = n.Time
+		p.Velocity = n.Velocity
+		p.Altitude = n.Altitude
+		p.Comment = n.Name
+
+		return nil // there is no additional data to parse
+	case '>':
+		st, ts, pos, err := ParseStatus(s[1:])
+		if err != nil {
+			return fieldError(err, 1, "status")
+		}
+		p.Status = &st
+		p.Time = ts
+		p.Position = pos
+		p.Symbol = st.Symbol
+
+		return nil // status text is not parsed for extensions
 	case '`', '\'':
 		pos, err := ParseMicE(s, p.Dst.Call)
 		if err != nil {
-			return err
+			return fieldError(err, 0, "position")
 		}
 		p.Position = &pos
 		p.parseMicEData()
+		if len(s) > 9 {
+			if dao, _, ok := ParseDAO(s[9:]); ok {
+				dao.Apply(p.Position)
+			}
+		}
 
 		return nil // there is no additional data to parse
 	default:
 		pos, txt, err := ParsePositionBoth(s)
 		if err != nil {
-			return err
+			return fieldError(err, 0, "position")
 		}
 		p.Position = &pos
 		p.data = txt
 	}
 
 	if p.Position != nil {
+		p.dataOffset = len(s) - len(p.data)
+		p.parseDAO()
 		if p.Position.Compressed {
 			return p.parseCompressedData()
 		}
@@ -285,6 +476,44 @@ func (p *Packet) parse() error {
 	return nil
 }
 
+// parsePositionAt parses the position at an offset in the payload, and takes
+// the symbol from it.
+func (p *Packet) parsePositionAt(s string, offset int, compressed bool) error {
+	pos, txt, err := ParsePosition(s[offset:], compressed)
+	if err != nil {
+		return fieldError(err, offset, "position")
+	}
+	p.Position = &pos
+	p.data = txt
+	if compressed {
+		p.Symbol = Symbol{s[offset], s[offset+9]}
+	} else {
+		p.Symbol = Symbol{s[offset+8], s[offset+18]}
+	}
+	return nil
+}
+
+func (p *Packet) parseObjectPosition(s string) error {
+	if len(s) < 1 {
+		return &ParseError{Field: "position", Err: ErrInvalidPosition}
+	}
+	compressed := !isDigit(s[0])
+	pos, txt, err := ParsePosition(s, compressed)
+	if err != nil {
+		return err
+	}
+	p.Position = &pos
+	p.data = txt
+	if compressed {
+		p.Symbol[0] = s[0]
+		p.Symbol[1] = s[9]
+	} else {
+		p.Symbol[0] = s[8]
+		p.Symbol[1] = s[18]
+	}
+	return nil
+}
+
 func (p *Packet) parseMicEData() error {
 	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 10, page 42 in PDF
 
@@ -311,26 +540,22 @@ func (p *Packet) parseMicEData() error {
 	p.Comment = miceMsgTypes[strings.Join(mt, "")]
 
 	// Speed and Course.
-	speed := float64(int(s[4])-28) * 10
-	dc := float64(int(s[5])-28) / 10
-	unit := float64(int(dc))
-	speed += unit
-	course := dc - unit
-	course += float64(int(s[6]) - 28)
+	dc := int(s[5]) - 28
+	speed := (int(s[4])-28)*10 + dc/10
+	course := (dc%10)*100 + int(s[6]) - 28
 	if speed >= 800 {
 		speed -= 800
 	}
-	speed = 1.852 * speed // convert speed from knots to km/h
 	if course >= 400 {
 		course -= 400
 	}
+	p.Velocity.Course = float64(course)
+	p.Velocity.Speed = Speed(speed) * Knot
 
 	// Symbol
 	p.Symbol[0] = s[7]
 	p.Symbol[1] = s[8]
 
-	p.Comment += fmt.Sprintf(" (%.fkm/h, %.f°)", speed, course)
-
 	// Check whether there's additional Telemetry or Status Text data.
 	if len(s) == 9 {
 		return nil
@@ -350,76 +575,189 @@ func (p *Packet) parseMicEData() error {
 	return nil
 }
 
+// parseDAO strips the !DAO! extension from the unparsed data and applies it
+// to the position.
+func (p *Packet) parseDAO() {
+	var o int
+	if p.Position.Compressed && len(p.data) >= 3 {
+		o = 3 // Skip csT bytes
+	}
+	if dao, txt, ok := ParseDAO(p.data[o:]); ok {
+		dao.Apply(p.Position)
+		p.data = p.data[:o] + txt
+	}
+}
+
 func (p *Packet) parseCompressedData() error {
 	// Parse csT bytes
-	if len(p.data) >= 3 {
-		// Compression Type (T) Byte Format
-		// Bit: 7      | 6      | 5       | 4     3     | 2    1    0      |
-		//	-------+--------+---------+-------------+------------------+
-		//      Unused | Unused | GPS Fix | NMEA Source | Origin           |
-		//	-------+--------+---------+-------------+------------------+
-		// Val: 0      | 0      | 0 = old | 00 = other  | 000 = Compressed |
-		//	       |        | 1 = cur | 01 = GLL    | 001 = TNC BTex   |
-		//	       |        |         | 10 = CGA    | 010 = Software   |
-		//	       |        |         | 11 = RMC    | 011 = [tbd]      |
-		//	       |        |         |             | 100 = KPC3       |
-		//	       |        |         |             | 101 = Pico       |
-		//	       |        |         |             | 110 = Other      |
-		//	       |        |         |             | 111 = Digipeater |
-		cb := p.data[0] - 33
-		sb := p.data[1] - 33
-		Tb := p.data[2] - 33
-		if p.data[0] != ' ' && ((Tb>>3)&3) == 2 {
-			// CGA sentence, NMEA Source = 0b10
-			d, err := base91Decode(p.data[0:2])
-			if err != nil {
-				return err
-			}
-			p.Altitude = math.Pow(1.002, float64(d))
-			p.Comment = p.data[3:]
-		} else if cb >= 0 && cb <= 89 { // !..z
-			// Course/Speed
-			p.Velocity.Course = float64(cb) * 4.0
-			p.Velocity.Speed = math.Pow(1.08, float64(sb)) - 1.0
-		} else if cb == 90 { // {
-			// Pre-Calculated Radio Range
-			p.Range = 2 * math.Pow(1.08, float64(sb))
+	if len(p.data) < 3 {
+		p.parseComment(p.data)
+		return nil
+	}
+
+	// Compression Type (T) Byte Format
+	// Bit: 7      | 6      | 5       | 4     3     | 2    1    0      |
+	//	-------+--------+---------+-------------+------------------+
+	//      Unused | Unused | GPS Fix | NMEA Source | Origin           |
+	//	-------+--------+---------+-------------+------------------+
+	// Val: 0      | 0      | 0 = old | 00 = other  | 000 = Compressed |
+	//	       |        | 1 = cur | 01 = GLL    | 001 = TNC BTex   |
+	//	       |        |         | 10 = CGA    | 010 = Software   |
+	//	       |        |         | 11 = RMC    | 011 = [tbd]      |
+	//	       |        |         |             | 100 = KPC3       |
+	//	       |        |         |             | 101 = Pico       |
+	//	       |        |         |             | 110 = Other      |
+	//	       |        |         |             | 111 = Digipeater |
+	cb := p.data[0] - 33
+	sb := p.data[1] - 33
+	Tb := p.data[2] - 33
+	if p.data[0] != ' ' && ((Tb>>3)&3) == 2 {
+		// CGA sentence, NMEA Source = 0b10
+		if d, err := base91Decode(p.data[0:2]); err != nil {
+			p.warn(p.dataOffset, "altitude", err)
+		} else {
+			p.Altitude = Altitude(Distance(math.Pow(1.002, float64(d))) * Foot)
 		}
+	} else if cb >= 0 && cb <= 89 { // !..z
+		// Course/Speed
+		p.Velocity.Course = float64(cb) * 4.0
+		p.Velocity.Speed = Speed(math.Pow(1.08, float64(sb))-1.0) * Knot
+	} else if cb == 90 { // {
+		// Pre-Calculated Radio Range
+		p.Range = Distance(2*math.Pow(1.08, float64(sb))) * Mile
 	}
+
+	p.parseComment(p.data[3:])
 	return nil
 }
 
 func (p *Packet) parseData() error {
+	var d = p.data
+
 	switch {
-	case len(p.data) >= 1 && p.data[0] == ' ':
-		p.Comment = p.data[1:]
-
-	case len(p.data) >= 7 && strings.HasPrefix(p.data, "PHG"):
-		p.PHG.PowerCode = p.data[3]
-		p.PHG.HeightCode = p.data[4]
-		p.PHG.GainCode = p.data[5]
-		p.PHG.DirectivityCode = p.data[6]
-		p.Range = math.Sqrt(2 * p.PHG.Height() * math.Sqrt((float64(p.PHG.Power())/10)*(float64(p.PHG.Gain())/2)))
-		p.Comment = p.data[7:]
-
-	case len(p.data) >= 7 && strings.HasPrefix(p.data, "RNG"):
-		var err error
-		p.Range, err = strconv.ParseFloat(p.data[3:7], 64)
-		if err != nil {
-			return err
+	case len(d) >= 1 && d[0] == ' ':
+		d = d[1:]
+
+	case len(d) >= 7 && strings.HasPrefix(d, "PHG"):
+		p.PHG.PowerCode = d[3]
+		p.PHG.HeightCode = d[4]
+		p.PHG.GainCode = d[5]
+		p.PHG.DirectivityCode = d[6]
+		p.Range = p.PHG.Range()
+		d = d[7:]
+
+	case len(d) >= 7 && strings.HasPrefix(d, "RNG"):
+		if r, err := strconv.ParseFloat(d[3:7], 64); err != nil {
+			p.warn(p.dataOffset+3, "range", err)
+		} else {
+			p.Range = Distance(r) * Mile
+			d = d[7:]
 		}
-		p.Comment = p.data[7:]
 
-	case len(p.data) >= 7 && strings.HasPrefix(p.data, "DFS"):
-		p.DFS.StrengthCode = p.data[3]
-		p.DFS.HeightCode = p.data[4]
-		p.DFS.GainCode = p.data[5]
-		p.DFS.DirectivityCode = p.data[6]
-		p.Comment = p.data[7:]
+	case len(d) >= 7 && strings.HasPrefix(d, "DFS"):
+		p.DFS.StrengthCode = d[3]
+		p.DFS.HeightCode = d[4]
+		p.DFS.GainCode = d[5]
+		p.DFS.DirectivityCode = d[6]
+		d = d[7:]
+
+	case len(d) >= 7 && p.Symbol == (Symbol{'\\', 'l'}) && isArea(d):
+		// Area object Tyy/Cxx
+		p.Area = &Area{
+			Type:            int(d[0] - '0'),
+			LatitudeOffset:  areaOffset(d[1:3]),
+			Color:           d[4],
+			LongitudeOffset: areaOffset(d[5:7]),
+		}
+		d = d[7:]
+		if len(d) >= 4 && d[0] == '{' && d[3] == '}' && isDigits(d[1:3]) {
+			// Line width {ww}
+			p.Area.LineWidth = int(d[1]-'0')*10 + int(d[2]-'0')
+			d = d[4:]
+		}
+
+	case len(d) >= 7 && isCourseSpeed(d):
+		course, speed := parseCourseSpeed(d)
+		if p.Symbol[1] == '_' {
+			// Weather station, wind direction and speed in mph
+			p.Wind.Direction = course
+			p.Wind.Speed = speed / Knot * MilePerHour
+		} else {
+			p.Velocity.Course = course
+			p.Velocity.Speed = speed
+		}
+		d = d[7:]
+
+		if len(d) >= 8 && d[0] == '/' && isCourseSpeed(d[1:]) && isDigits(d[5:8]) {
+			// DF bearing and number/range/quality /BRG/NRQ
+			bearing, _ := parseCourseSpeed(d[1:])
+			p.DF = &DFBearing{
+				Bearing:     bearing,
+				NumberCode:  d[5],
+				RangeCode:   d[6],
+				QualityCode: d[7],
+			}
+			d = d[8:]
+		}
 	}
+
+	p.parseComment(d)
 	return nil
 }
 
+// parseComment extracts the data embedded in the comment text.
+func (p *Packet) parseComment(s string) {
+	// Signpost {nnn}
+	if p.Symbol == (Symbol{'\\', 'm'}) && len(s) >= 3 && s[0] == '{' {
+		if i := strings.IndexByte(s, '}'); i > 1 && i <= 4 {
+			p.Signpost = s[1:i]
+			s = s[i+1:]
+		}
+	}
+
+	// Altitude /A=nnnnnn, which may appear anywhere in the comment
+	if i := strings.Index(s, "/A="); i >= 0 && len(s) >= i+9 {
+		if a, err := strconv.ParseInt(s[i+3:i+9], 10, 32); err == nil {
+			p.Altitude = Altitude(Distance(a) * Foot)
+			s = s[:i] + s[i+9:]
+		} else {
+			p.warn(p.dataOffset+len(p.data)-len(s)+i+3, "altitude", err)
+		}
+	}
+
+	p.Comment = s
+}
+
+func isCourseSpeed(s string) bool {
+	if len(s) < 7 || s[3] != '/' {
+		return false
+	}
+	for _, c := range s[:3] + s[4:7] {
+		if !(c >= '0' && c <= '9') && c != '.' && c != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCourseSpeed parses a ccc/sss extension, unknown values are 0.
+func parseCourseSpeed(s string) (float64, Speed) {
+	course, _ := strconv.ParseFloat(s[0:3], 64)
+	speed, _ := strconv.ParseFloat(s[4:7], 64)
+	return course, Speed(speed) * Knot
+}
+
+func isArea(s string) bool {
+	return isDigit(s[0]) && isDigits(s[1:3]) && s[3] == '/' && isDigits(s[5:7])
+}
+
+// areaOffset decodes an area object offset, it is encoded as the square
+// root of the offset in hundredths of a degree.
+func areaOffset(s string) float64 {
+	v := float64(int(s[0]-'0')*10 + int(s[1]-'0'))
+	return v * v / 100
+}
+
 func (p Payload) Time() (time.Time, error) {
 	switch p.Type() {
 	case '/', '@':
@@ -428,3 +766,35 @@ func (p Payload) Time() (time.Time, error) {
 		return time.Time{}, nil
 	}
 }
+
+// Timestamp returns the raw time stamp of the payload, or an empty string if
+// the payload has no time stamp.
+func (p Payload) Timestamp() string {
+	s := string(p)
+	switch p.Type() {
+	case '/', '@', '>':
+		if len(s) >= 8 && isDigits(s[1:7]) && (s[7] == 'z' || s[7] == '/' || s[7] == 'h') {
+			return s[1:8]
+		}
+	case ';':
+		if len(s) >= 18 && isDigits(s[11:17]) {
+			return s[11:18]
+		}
+	case '_':
+		if len(s) >= 9 && isDigits(s[1:9]) {
+			return s[1:9]
+		}
+	}
+	return ""
+}
+
+// ResolveTime returns the absolute time of the packet time stamp, relative to
+// the time the packet was received, see ResolveTime. If the packet has no
+// time stamp, the receive time is returned.
+func (p Packet) ResolveTime(received time.Time, loc *time.Location) (time.Time, bool, error) {
+	ts := p.Payload.Timestamp()
+	if ts == "" {
+		return received, false, nil
+	}
+	return ResolveTime(ts, received, loc)
+}
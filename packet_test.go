@@ -1,32 +1,24 @@
 package aprs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"math"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
-const earthRadius = float64(6378100)
-
 func testTime(day, hour, min, sec int) *time.Time {
 	t := time.Date(0, 0, day, hour, min, sec, 0, time.UTC)
 	return &t
 }
 
-// haversin(θ) function
-func testHaversin(theta float64) float64 {
-	return math.Pow(math.Sin(theta/2), 2)
-}
-
 func testDistance(a *Position, b *Position) float64 {
-	var (
-		lat1 = a.Latitude * math.Pi / 180
-		lng1 = a.Longitude * math.Pi / 180
-		lat2 = b.Latitude * math.Pi / 180
-		lng2 = b.Longitude * math.Pi / 180
-		h    = testHaversin(lat2-lat1) + math.Cos(lat1)*math.Cos(lat2)*testHaversin(lng2-lng1)
-	)
-	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+	return a.DistanceTo(*b).Meters()
 }
 
 func TestPacket(t *testing.T) {
@@ -40,8 +32,8 @@ func TestPacket(t *testing.T) {
 		Velocity *Velocity
 		PHG      *PowerHeightGain
 		DFS      *OmniDFStrength
-		Altitude float64
-		Range    float64
+		Altitude float64 // Feet
+		Range    float64 // Miles
 		Time     *time.Time
 	}{
 		{
@@ -59,6 +51,7 @@ func TestPacket(t *testing.T) {
 			PathLen:  1,
 			Type:     DataType('!'),
 			Position: &Position{Latitude: 49.058333, Longitude: -72.029167},
+			Altitude: 1234,
 		},
 		{
 			Raw:      "N0CALL>APRS,qAC:!49  .  N/072  .  W-",
@@ -111,6 +104,7 @@ func TestPacket(t *testing.T) {
 			PathLen:  1,
 			Type:     DataType('@'),
 			Position: &Position{Latitude: 49.058333, Longitude: -72.029167},
+			Velocity: &Velocity{88, 36 * Knot},
 			Time:     testTime(9, 23, 45, 0),
 		},
 		{
@@ -207,7 +201,7 @@ func TestPacket(t *testing.T) {
 			PathLen:  4,
 			Type:     DataType('='),
 			Position: &Position{Latitude: 49.5, Longitude: -72.75},
-			Velocity: &Velocity{88.0, 36.2},
+			Velocity: &Velocity{88.0, 36.2 * Knot},
 		},
 		{
 			Raw:      "N0CALL-1>T3PY1Y,KQ1L-8*,WIDE1,WIDE2-1,qAR:=/5L!!<*e7>{?!",
@@ -237,6 +231,50 @@ func TestPacket(t *testing.T) {
 			Position: &Position{Latitude: 49.5, Longitude: -72.75},
 			Range:    20.13,
 		},
+		{
+			Raw:      "N0CALL>S32U6T,qAR:`d#f$lt>/",
+			Src:      MustParseAddress("N0CALL"),
+			Dst:      MustParseAddress("S32U6T"),
+			PathLen:  1,
+			Type:     DataType('`'),
+			Position: &Position{Latitude: 33.427333, Longitude: -72.129000},
+			Velocity: &Velocity{88, 88 * Knot},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:$GPRMC,063909,A,3349.4302,N,11700.3721,W,43.022,89.3,291099,13.6,E*52",
+			Src:      MustParseAddress("N0CALL"),
+			Dst:      MustParseAddress("APRS"),
+			PathLen:  1,
+			Type:     DataType('$'),
+			Position: &Position{Latitude: 33.823837, Longitude: -117.006202},
+			Velocity: &Velocity{89.3, 43.022 * Knot},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:$GPGGA,102705,5157.9762,N,00029.3256,W,1,04,2.0,75.7,M,47.6,M,,*62",
+			Src:      MustParseAddress("N0CALL"),
+			Dst:      MustParseAddress("APRS"),
+			PathLen:  1,
+			Type:     DataType('$'),
+			Position: &Position{Latitude: 51.966270, Longitude: -0.488760},
+			Altitude: 248,
+			Time:     testTime(0, 10, 27, 5),
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:$GPGLL,4916.45,N,12311.12,W,225444,A*31",
+			Src:      MustParseAddress("N0CALL"),
+			Dst:      MustParseAddress("APRS"),
+			PathLen:  1,
+			Type:     DataType('$'),
+			Position: &Position{Latitude: 49.274167, Longitude: -123.185333},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:$GPWPL,4807.038,N,01131.000,E,WPTNME*5C",
+			Src:      MustParseAddress("N0CALL"),
+			Dst:      MustParseAddress("APRS"),
+			PathLen:  1,
+			Type:     DataType('$'),
+			Position: &Position{Latitude: 48.117300, Longitude: 11.516667},
+		},
 	}
 
 	for _, test := range tests {
@@ -262,8 +300,8 @@ func TestPacket(t *testing.T) {
 			if p.Altitude == 0 {
 				t.Fatalf("expected altitude %f, got none", test.Altitude)
 			}
-			if math.Abs(test.Altitude-p.Altitude) > 1.0 {
-				t.Fatalf("expected altitude %f, got %f", test.Altitude, p.Altitude)
+			if math.Abs(test.Altitude-p.Altitude.Feet()) > 1.0 {
+				t.Fatalf("expected altitude %f, got %f", test.Altitude, p.Altitude.Feet())
 			}
 		}
 		if test.Velocity != nil {
@@ -273,16 +311,16 @@ func TestPacket(t *testing.T) {
 			if math.Abs(test.Velocity.Course-p.Velocity.Course) > 1.0 {
 				t.Fatalf("expected course %f, got %f", test.Velocity.Course, p.Velocity.Course)
 			}
-			if math.Abs(test.Velocity.Speed-p.Velocity.Speed) > 1.0 {
-				t.Fatalf("expected speed %f, got %f", test.Velocity.Speed, p.Velocity.Speed)
+			if math.Abs(test.Velocity.Speed.Knots()-p.Velocity.Speed.Knots()) > 1.0 {
+				t.Fatalf("expected speed %s, got %s", test.Velocity.Speed, p.Velocity.Speed)
 			}
 		}
 		if test.Range != 0 {
 			if p.Range == 0 {
 				t.Fatalf("expected range %f, got none", test.Range)
 			}
-			if math.Abs(test.Range-p.Range) > 0.1 {
-				t.Fatalf("expected range %f, got %f", test.Range, p.Range)
+			if math.Abs(test.Range-p.Range.Miles()) > 0.1 {
+				t.Fatalf("expected range %f, got %f", test.Range, p.Range.Miles())
 			}
 		}
 		if test.Position != nil {
@@ -303,3 +341,1228 @@ func TestPacket(t *testing.T) {
 		}
 	}
 }
+
+func TestPacketStatus(t *testing.T) {
+	var tests = []struct {
+		Raw      string
+		Text     string
+		Locator  string
+		Symbol   Symbol
+		Position *Position
+		Time     *time.Time
+		Heading  float64
+		ERP      int
+	}{
+		{
+			Raw:  "N0CALL>APRS,qAC:>Net Control Center",
+			Text: "Net Control Center",
+		},
+		{
+			Raw:  "N0CALL>APRS,qAC:>092345zNet Control Center",
+			Text: "Net Control Center",
+			Time: testTime(9, 23, 45, 0),
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:>IO91SX/G",
+			Locator:  "IO91SX",
+			Symbol:   Symbol{'/', 'G'},
+			Position: &Position{Latitude: 51.958333, Longitude: -0.500000},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:>IO91/G My house",
+			Text:     "My house",
+			Locator:  "IO91",
+			Symbol:   Symbol{'/', 'G'},
+			Position: &Position{Latitude: 51.0, Longitude: -2.0},
+		},
+		{
+			Raw:     "N0CALL>APRS,qAC:>Beam Heading^B7",
+			Text:    "Beam Heading",
+			Heading: 110,
+			ERP:     490,
+		},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		if p.Status == nil {
+			t.Fatalf("%q: expected status, got none", test.Raw)
+		}
+		if p.Status.Text != test.Text {
+			t.Fatalf("expected text %q, got %q", test.Text, p.Status.Text)
+		}
+		if p.Status.Locator != test.Locator {
+			t.Fatalf("expected locator %q, got %q", test.Locator, p.Status.Locator)
+		}
+		if p.Status.Symbol != test.Symbol {
+			t.Fatalf("expected symbol %q, got %q", test.Symbol[:], p.Status.Symbol[:])
+		}
+		if test.Position != nil {
+			if p.Position == nil {
+				t.Fatalf("expected position %s, got none", test.Position)
+			}
+			if d := testDistance(test.Position, p.Position); d > 1.0 {
+				t.Fatalf("expected position %s, got %s with distance %f meter", test.Position, p.Position, d)
+			}
+		}
+		if test.Time != nil {
+			if p.Time == nil {
+				t.Fatalf("expected time %s", test.Time)
+			}
+			if test.Time.Sub(*p.Time) > time.Minute {
+				t.Fatalf("expected time %s, got %s", test.Time, p.Time)
+			}
+		}
+		if test.ERP != 0 {
+			if !p.Status.HasBeam() {
+				t.Fatalf("expected beam heading, got none")
+			}
+			if p.Status.Beam.Heading() != test.Heading || p.Status.Beam.ERP() != test.ERP {
+				t.Fatalf("expected beam %f/%d, got %f/%d", test.Heading, test.ERP, p.Status.Beam.Heading(), p.Status.Beam.ERP())
+			}
+		}
+	}
+}
+
+func TestPacketQuery(t *testing.T) {
+	var tests = []struct {
+		Raw       string
+		Type      string
+		Directed  bool
+		Target    string
+		Footprint *Footprint
+	}{
+		{
+			Raw:  "N0CALL>APRS,qAC:?APRS?",
+			Type: QueryAPRS,
+		},
+		{
+			Raw:  "N0CALL>APRS,qAC:?IGATE?",
+			Type: QueryIGate,
+		},
+		{
+			Raw:       "N0CALL>APRS,qAC:?APRS? 34.02,-117.15,0200",
+			Type:      QueryAPRS,
+			Footprint: &Footprint{Latitude: 34.02, Longitude: -117.15, Radius: 200 * Mile},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC::PA4TW    :?APRSP{12",
+			Type:     QueryPosition,
+			Directed: true,
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC::PA4TW    :?APRSH PA4TW-10",
+			Type:     QueryHeard,
+			Directed: true,
+			Target:   "PA4TW-10",
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC::PA4TW    :?PING?",
+			Type:     QueryPing,
+			Directed: true,
+		},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		if p.Query == nil {
+			t.Fatalf("%q: expected query, got none", test.Raw)
+		}
+		if p.Query.Type != test.Type || p.Query.Directed != test.Directed || p.Query.Target != test.Target {
+			t.Fatalf("expected query %+v, got %+v", test, p.Query)
+		}
+		if test.Footprint != nil && (p.Query.Footprint == nil || *p.Query.Footprint != *test.Footprint) {
+			t.Fatalf("expected footprint %+v, got %+v", test.Footprint, p.Query.Footprint)
+		}
+	}
+}
+
+func TestStationRespond(t *testing.T) {
+	st := Station{
+		Address:  MustParseAddress("PA4TW"),
+		Position: &Position{Latitude: 49.058333, Longitude: -72.029167},
+		Symbol:   Symbol{'/', '-'},
+		Comment:  "Test",
+		Status:   "On the air",
+		MessageCount: func() int {
+			return 2
+		},
+		LocalCount: func() int {
+			return 3
+		},
+	}
+
+	var tests = []struct {
+		Raw   string
+		Reply string
+	}{
+		{"N0CALL>APRS,qAC:?APRS?", "=4903.50N/07201.75W-Test"},
+		{"N0CALL>APRS,qAC:?APRS? 49.0,-72.0,10", "=4903.50N/07201.75W-Test"},
+		{"N0CALL>APRS,qAC:?APRS? 52.0,5.0,10", ""},
+		{"N0CALL>APRS,qAC:?IGATE?", "<IGATE,MSG_CNT=2,LOC_CNT=3"},
+		{"N0CALL>APRS,qAC::PA4TW    :?APRSS", ">On the air"},
+		{"N0CALL>APRS,qAC::PA4TW-1  :?APRSS", ""},
+		{"N0CALL>APRS,WIDE1-1,WIDE2-1::PA4TW    :?APRST", ":N0CALL   :N0CALL>APRS,WIDE1-1,WIDE2-1"},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		r := st.Respond(p)
+		if test.Reply == "" {
+			if len(r) != 0 {
+				t.Fatalf("%q: expected no reply, got %q", test.Raw, r)
+			}
+			continue
+		}
+		if len(r) != 1 || string(r[0]) != test.Reply {
+			t.Fatalf("%q: expected reply %q, got %q", test.Raw, test.Reply, r)
+		}
+	}
+
+	// Outstanding messages and objects, and ?IGATE? with partial counts.
+	st.LocalCount = nil
+	st.Messages = func(a *Address) []Message {
+		return []Message{{Addressee: a.String(), Text: "Hello", ID: "1"}, {Addressee: a.String(), Text: "Bye", ID: "2"}}
+	}
+	st.Objects = func() []PositionReport {
+		return []PositionReport{{
+			Position: Position{Latitude: 49.058333, Longitude: -72.029167, Symbol: Symbol{'/', '-'}},
+			Object:   &Object{Name: "HOME", Item: true},
+		}}
+	}
+	var more = []struct {
+		Raw     string
+		Replies []string
+	}{
+		{"N0CALL>APRS,qAC:?IGATE?", []string{"<IGATE,MSG_CNT=2"}},
+		{"N0CALL>APRS,qAC::PA4TW    :?APRSM", []string{":N0CALL   :Hello{1", ":N0CALL   :Bye{2"}},
+		{"N0CALL>APRS,qAC::PA4TW    :?APRSO", []string{")HOME!4903.50N/07201.75W-"}},
+		{"N0CALL>APRS,qAC:?APRSO?", nil},
+	}
+	for _, test := range more {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		r := st.Respond(p)
+		if len(r) != len(test.Replies) {
+			t.Fatalf("%q: expected replies %q, got %q", test.Raw, test.Replies, r)
+		}
+		for i := range r {
+			if string(r[i]) != test.Replies[i] {
+				t.Errorf("%q: expected reply %q, got %q", test.Raw, test.Replies[i], r[i])
+			}
+		}
+	}
+}
+
+func TestPacketCapabilities(t *testing.T) {
+	p, err := ParsePacket("N0CALL>APRS,qAC:<IGATE,MSG_CNT=12,LOC_CNT=34,FOO")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Capabilities.IsIGate() {
+		t.Fatalf("expected IGate capability, got %v", p.Capabilities)
+	}
+	if n, ok := p.Capabilities.MessageCount(); !ok || n != 12 {
+		t.Fatalf("expected message count 12, got %d", n)
+	}
+	if n, ok := p.Capabilities.LocalCount(); !ok || n != 34 {
+		t.Fatalf("expected local count 34, got %d", n)
+	}
+	if !p.Capabilities.Has("FOO") {
+		t.Fatalf("expected FOO capability, got %v", p.Capabilities)
+	}
+	if s := p.Capabilities.String(); s != string(p.Payload) {
+		t.Fatalf("expected %q, got %q", p.Payload, s)
+	}
+}
+
+func TestPacketThirdParty(t *testing.T) {
+	p, err := ParsePacket("PA4TW-2>APRS,WIDE2-1:}N0CALL>APRS,TCPIP,PA4TW-2*:!4903.50N/07201.75W-Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ThirdParty == nil {
+		t.Fatal("expected third-party packet, got none")
+	}
+	if !p.Src.EqualTo(MustParseAddress("PA4TW-2")) {
+		t.Fatalf("expected envelope src PA4TW-2, got %s", p.Src)
+	}
+	o := p.Unwrap()
+	if !o.Src.EqualTo(MustParseAddress("N0CALL")) {
+		t.Fatalf("expected src N0CALL, got %s", o.Src)
+	}
+	if o.Position == nil {
+		t.Fatal("expected position, got none")
+	}
+
+	e := o.Encapsulate(MustParseAddress("PA4TW-2"), MustParseAddress("APRS"), Path{MustParseAddress("WIDE2-1")})
+	if e.String() != p.Raw {
+		t.Fatalf("expected %q, got %q", p.Raw, e.String())
+	}
+	if e.ThirdParty == nil || e.Unwrap().Position == nil {
+		t.Fatalf("expected encapsulated position, got none")
+	}
+}
+
+func TestPacketNMEA(t *testing.T) {
+	p, err := ParsePacket("N0CALL>APRS,qAC:$GPGGA,102705,5157.9762,N,00029.3256,W,1,04,2.0,75.7,M,47.6,M,,*62")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Fix == nil || !p.Fix.Valid || p.Fix.Quality != 1 || p.Fix.Satellites != 4 {
+		t.Fatalf("expected valid fix with 4 satellites, got %+v", p.Fix)
+	}
+
+	if _, err = ParsePacket("N0CALL>APRS,qAC:$GPGLL,4916.45,N,12311.12,W,225444,A*32"); !errors.Is(err, ErrNMEAChecksum) {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+
+	p, err = ParsePacket("N0CALL>APRS,qAC:$ULTW0031003702CE0069----000086A00001----011901CC00000005")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Weather == nil || p.Weather.Temperature == nil || math.Abs(p.Weather.Temperature.Fahrenheit()-71.8) > 0.01 {
+		t.Fatalf("expected temperature 71.8, got %+v", p.Weather)
+	}
+	if p.Weather.Pressure != nil || p.Weather.Humidity != nil {
+		t.Fatalf("expected no pressure and humidity, got %+v", p.Weather)
+	}
+	if math.Abs(p.Wind.Direction-77.3) > 0.1 {
+		t.Fatalf("expected wind direction 77.3, got %f", p.Wind.Direction)
+	}
+	if math.Abs(p.Wind.Gust.KilometersPerHour()-4.9) > 0.01 {
+		t.Fatalf("expected wind gust 4.9km/h, got %s", p.Wind.Gust)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	var tests = []struct {
+		Value  interface{ Format(UnitSystem) string }
+		Units  UnitSystem
+		Expect string
+	}{
+		{10 * Mile, Metric, "16.1km"},
+		{10 * Mile, Imperial, "10.0mi"},
+		{10 * NauticalMile, Nautical, "10.0NM"},
+		{500 * Meter, Metric, "500m"},
+		{Altitude(1000 * Foot), Metric, "305m"},
+		{Altitude(1000 * Foot), Imperial, "1000ft"},
+		{100 * KilometerPerHour, Metric, "100km/h"},
+		{100 * KilometerPerHour, Imperial, "62mph"},
+		{100 * KilometerPerHour, Nautical, "54kn"},
+		{Fahrenheit(212), Metric, "100.0°C"},
+		{Temperature(-40), Imperial, "-40.0°F"},
+		{1013.25 * Hectopascal, Metric, "1013.2hPa"},
+		{1013.25 * Hectopascal, Imperial, "29.92inHg"},
+	}
+
+	for _, test := range tests {
+		if s := test.Value.Format(test.Units); s != test.Expect {
+			t.Fatalf("expected %q, got %q", test.Expect, s)
+		}
+	}
+
+	// String is always metric.
+	if s := (10 * Mile).String(); s != "16.1km" {
+		t.Fatalf("expected %q, got %q", "16.1km", s)
+	}
+}
+
+func TestPacketDAO(t *testing.T) {
+	var tests = []struct {
+		Raw       string
+		Datum     byte
+		Precision float64
+		Position  *Position
+		Comment   string
+	}{
+		{
+			Raw:       "N0CALL>APRS,qAC:!4903.50N/07201.75W- Test!W12!",
+			Datum:     'W',
+			Precision: 1.852,
+			Position:  &Position{Latitude: 49.0583500, Longitude: -72.0292000},
+			Comment:   "Test",
+		},
+		{
+			Raw:       "N0CALL>APRS,qAC:!4903.50N/07201.75W- Test !w!(! rest",
+			Datum:     'w',
+			Precision: 0.1852,
+			Position:  &Position{Latitude: 49.0583333, Longitude: -72.0291795},
+			Comment:   "Test  rest",
+		},
+		{
+			Raw:       "N0CALL>APRS,qAC:!4903.50N/07201.75W- Test",
+			Precision: 18.52,
+			Position:  &Position{Latitude: 49.058333, Longitude: -72.029167},
+			Comment:   "Test",
+		},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		if p.Position.Datum != test.Datum {
+			t.Fatalf("expected datum %q, got %q", test.Datum, p.Position.Datum)
+		}
+		if math.Abs(p.Position.Precision-test.Precision) > 0.001 {
+			t.Fatalf("expected precision %f, got %f", test.Precision, p.Position.Precision)
+		}
+		if d := testDistance(test.Position, p.Position); d > 0.1 {
+			t.Fatalf("expected position %s, got %s with distance %f meter", test.Position, p.Position, d)
+		}
+		if p.Comment != test.Comment {
+			t.Fatalf("expected comment %q, got %q", test.Comment, p.Comment)
+		}
+
+		// Encode and parse again, the position should survive.
+		var (
+			pos = *p.Position
+			raw = "N0CALL>APRS:!" + FormatUncompressedPosition(pos) + FormatDAO(pos)
+		)
+		q, err := ParsePacket(raw)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		if d := testDistance(p.Position, q.Position); d > 0.1 {
+			t.Fatalf("%q: expected position %s, got %s with distance %f meter", raw, p.Position, q.Position, d)
+		}
+	}
+}
+
+func TestPacketExtensions(t *testing.T) {
+	var tests = []struct {
+		Raw      string
+		Comment  string
+		Altitude float64 // Feet
+		Velocity Velocity
+		Wind     Wind
+		DF       *DFBearing
+		Area     *Area
+		Signpost string
+	}{
+		{
+			Raw:      "N0CALL>APRS,qAC:!4903.50N/07201.75W-Test /A=001234 more",
+			Comment:  "Test  more",
+			Altitude: 1234,
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:!4903.50N/07201.75W>088/036/A=-00012Driving",
+			Comment:  "Driving",
+			Altitude: -12,
+			Velocity: Velocity{88, 36 * Knot},
+		},
+		{
+			Raw:     "N0CALL>APRS,qAC:!4903.50N/07201.75W_220/004g005t077",
+			Comment: "g005t077",
+			Wind:    Wind{Direction: 220, Speed: 4 * MilePerHour},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:!4903.50N\\07201.75W\\088/036/270/729Fox",
+			Comment:  "Fox",
+			Velocity: Velocity{88, 36 * Knot},
+			DF:       &DFBearing{270, '7', '2', '9'},
+		},
+		{
+			Raw:     "N0CALL>APRS,qAC:;AREA     *092345z4903.50N\\07201.75Wl107/510{05}Exercise",
+			Comment: "Exercise",
+			Area:    &Area{Type: 1, LatitudeOffset: 0.49, LongitudeOffset: 1, Color: '5', LineWidth: 5},
+		},
+		{
+			Raw:      "N0CALL>APRS,qAC:!4903.50N\\07201.75Wm{55}Speed limit",
+			Comment:  "Speed limit",
+			Signpost: "55",
+		},
+		{
+			Raw:      "N0CALL-1>APRS,qAR:=/5L!!<*e7> sT/A=000500",
+			Altitude: 500,
+		},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		if p.Comment != test.Comment {
+			t.Fatalf("%q: expected comment %q, got %q", test.Raw, test.Comment, p.Comment)
+		}
+		if math.Abs(p.Altitude.Feet()-test.Altitude) > 0.01 {
+			t.Fatalf("%q: expected altitude %f, got %f", test.Raw, test.Altitude, p.Altitude.Feet())
+		}
+		if p.Velocity.Course != test.Velocity.Course || math.Abs(float64(p.Velocity.Speed-test.Velocity.Speed)) > 0.01 {
+			t.Fatalf("%q: expected velocity %v, got %v", test.Raw, test.Velocity, p.Velocity)
+		}
+		if p.Wind.Direction != test.Wind.Direction || math.Abs(float64(p.Wind.Speed-test.Wind.Speed)) > 0.01 {
+			t.Fatalf("%q: expected wind %v, got %v", test.Raw, test.Wind, p.Wind)
+		}
+		if test.DF != nil {
+			if p.DF == nil || *p.DF != *test.DF {
+				t.Fatalf("%q: expected DF %+v, got %+v", test.Raw, test.DF, p.DF)
+			}
+			if p.DF.Hits() != 87.5 || p.DF.Range().Miles() != 4 || p.DF.Accuracy() != 1 {
+				t.Fatalf("%q: unexpected DF hits %f, range %f, accuracy %f", test.Raw, p.DF.Hits(), p.DF.Range().Miles(), p.DF.Accuracy())
+			}
+		}
+		if test.Area != nil && (p.Area == nil || *p.Area != *test.Area) {
+			t.Fatalf("%q: expected area %+v, got %+v", test.Raw, test.Area, p.Area)
+		}
+		if p.Signpost != test.Signpost {
+			t.Fatalf("%q: expected signpost %q, got %q", test.Raw, test.Signpost, p.Signpost)
+		}
+	}
+}
+
+func TestPositionAmbiguity(t *testing.T) {
+	var tests = []struct {
+		Raw       string
+		Ambiguity int
+		Center    Position
+		Bounds    Box
+	}{
+		{
+			Raw:       "N0CALL>APRS:!4903.5 N/07201.7 W-",
+			Ambiguity: 1,
+			Center:    Position{Latitude: 49.059167, Longitude: -72.029167},
+			Bounds:    Box{South: 49.058333, West: -72.030000, North: 49.060000, East: -72.028333},
+		},
+		{
+			Raw:       "N0CALL>APRS:!4903.  N/07201.  W-",
+			Ambiguity: 2,
+			Center:    Position{Latitude: 49.058333, Longitude: -72.025000},
+			Bounds:    Box{South: 49.05, West: -72.033333, North: 49.066667, East: -72.016667},
+		},
+		{
+			Raw:       "N0CALL>APRS:!49  .  N/072  .  W-",
+			Ambiguity: 4,
+			Center:    Position{Latitude: 49.5, Longitude: -72.5},
+			Bounds:    Box{South: 49, West: -73, North: 50, East: -72},
+		},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		if p.Position.Ambiguity != test.Ambiguity {
+			t.Fatalf("%q: expected ambiguity %d, got %d", test.Raw, test.Ambiguity, p.Position.Ambiguity)
+		}
+		c := p.Position.Center()
+		if d := testDistance(&test.Center, &c); d > 1.0 {
+			t.Fatalf("%q: expected center %s, got %s", test.Raw, test.Center, c)
+		}
+		b := p.Position.Bounds()
+		if math.Abs(b.South-test.Bounds.South) > 1e-5 || math.Abs(b.North-test.Bounds.North) > 1e-5 ||
+			math.Abs(b.West-test.Bounds.West) > 1e-5 || math.Abs(b.East-test.Bounds.East) > 1e-5 {
+			t.Fatalf("%q: expected bounds %+v, got %+v", test.Raw, test.Bounds, b)
+		}
+		if !b.Contains(c) {
+			t.Fatalf("%q: expected bounds %+v to contain center %s", test.Raw, b, c)
+		}
+		if r := p.Position.AmbiguityRadius(); r <= 0 {
+			t.Fatalf("%q: expected ambiguity radius, got %f", test.Raw, r)
+		}
+
+		pos := *p.Position
+		pos.Symbol = p.Symbol
+		if s := "N0CALL>APRS:!" + FormatUncompressedPosition(pos); s != test.Raw {
+			t.Fatalf("expected %q, got %q", test.Raw, s)
+		}
+		q, _, err := ParseCompressedPosition(FormatCompressedPosition(pos))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := testDistance(&c, &q); d > 1.0 {
+			t.Fatalf("%q: expected compressed position %s, got %s", test.Raw, c, q)
+		}
+	}
+}
+
+func TestPositionGeodesy(t *testing.T) {
+	var (
+		a = Position{Latitude: 52.205, Longitude: 0.119}
+		b = Position{Latitude: 48.857, Longitude: 2.351}
+	)
+
+	if d := a.DistanceTo(b).Kilometers(); math.Abs(d-404.3) > 0.1 {
+		t.Fatalf("expected distance 404.3km, got %f", d)
+	}
+	if d, err := a.VincentyDistanceTo(b); err != nil || math.Abs(d.Kilometers()-404.6) > 0.1 {
+		t.Fatalf("expected Vincenty distance 404.6km, got %s (%v)", d, err)
+	}
+	if brg := a.BearingTo(b); math.Abs(brg-156.2) > 0.1 {
+		t.Fatalf("expected bearing 156.2, got %f", brg)
+	}
+
+	m := a.Midpoint(b)
+	if d := testDistance(&m, &Position{Latitude: 50.5363, Longitude: 1.2746}); d > 10 {
+		t.Fatalf("expected midpoint 50.5363, 1.2746, got %s", m)
+	}
+
+	c := a.Destination(a.BearingTo(b), a.DistanceTo(b))
+	if d := testDistance(&c, &b); d > 1 {
+		t.Fatalf("expected destination %s, got %s", b, c)
+	}
+
+	if !b.WithinRadius(a, 405*Kilometer) || b.WithinRadius(a, 200*Mile) {
+		t.Fatalf("expected %s within 405km and not within 200mi of %s", b, a)
+	}
+	if !b.Within(BoxAround(b, 10*NauticalMile)) || a.Within(BoxAround(b, 10*NauticalMile)) {
+		t.Fatalf("expected box around %s to contain it and not %s", b, a)
+	}
+}
+
+func TestPositionLocator(t *testing.T) {
+	var pos = Position{Latitude: 51.5074, Longitude: -0.1278}
+
+	var tests = []struct {
+		Precision int
+		Locator   string
+	}{
+		{2, "IO"},
+		{4, "IO91"},
+		{6, "IO91wm"},
+		{8, "IO91wm41"},
+		{10, "IO91wm41ps"},
+	}
+	for _, test := range tests {
+		l, err := pos.Locator(test.Precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l != test.Locator {
+			t.Fatalf("expected locator %q, got %q", test.Locator, l)
+		}
+
+		// The beacon should parse back to the corner of the locator.
+		b, err := FormatGridBeacon(pos, test.Precision, "London")
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := ParsePacket("N0CALL>APRS:" + b)
+		if err != nil {
+			t.Fatalf("%q: %v", b, err)
+		}
+		if p.Comment != "London" {
+			t.Fatalf("%q: expected comment %q, got %q", b, "London", p.Comment)
+		}
+		if l, _ := p.Position.Locator(test.Precision); l != test.Locator {
+			t.Fatalf("%q: expected locator %q, got %q", b, test.Locator, l)
+		}
+	}
+
+	if _, err := pos.Locator(5); err == nil {
+		t.Fatal("expected error for locator precision 5")
+	}
+
+	st, err := NewLocatorStatus(pos, 6, Symbol{'/', 'G'}, "London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := st.String(); s != ">IO91WM/G London" {
+		t.Fatalf("expected status %q, got %q", ">IO91WM/G London", s)
+	}
+	p, err := ParsePacket("N0CALL>APRS:" + st.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status.Text != "London" || p.Status.Locator != "IO91WM" {
+		t.Fatalf("expected status %+v, got %+v", st, p.Status)
+	}
+}
+
+func TestResolveTime(t *testing.T) {
+	var (
+		received  = time.Date(2020, time.March, 1, 0, 30, 0, 0, time.UTC)
+		amsterdam = time.FixedZone("CET", 3600)
+	)
+
+	var tests = []struct {
+		Raw  string
+		Loc  *time.Location
+		Time time.Time
+		Zulu bool
+	}{
+		{"N0CALL>APRS:@292345z4903.50N/07201.75W>", nil, time.Date(2020, time.February, 29, 23, 45, 0, 0, time.UTC), true},
+		{"N0CALL>APRS:@010015z4903.50N/07201.75W>", nil, time.Date(2020, time.March, 1, 0, 15, 0, 0, time.UTC), true},
+		{"N0CALL>APRS:@234517h4903.50N/07201.75W>", nil, time.Date(2020, time.February, 29, 23, 45, 17, 0, time.UTC), true},
+		{"N0CALL>APRS:@010120/4903.50N/07201.75W>", amsterdam, time.Date(2020, time.March, 1, 0, 20, 0, 0, time.UTC), false},
+		{"N0CALL>APRS:>312359zStatus", nil, time.Date(2020, time.January, 31, 23, 59, 0, 0, time.UTC), true},
+		{"N0CALL>APRS:;OBJECT   *302345z4903.50N/07201.75W>", nil, time.Date(2020, time.January, 30, 23, 45, 0, 0, time.UTC), true},
+		{"N0CALL>APRS:_12312359c220s004g005t077", nil, time.Date(2019, time.December, 31, 23, 59, 0, 0, time.UTC), true},
+		{"N0CALL>APRS:!4903.50N/07201.75W>", nil, received, false},
+	}
+
+	for _, test := range tests {
+		p, _ := ParsePacket(test.Raw)
+		ts, zulu, err := p.ResolveTime(received, test.Loc)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		if !ts.Equal(test.Time) {
+			t.Fatalf("%q: expected time %s, got %s", test.Raw, test.Time, ts)
+		}
+		if zulu != test.Zulu {
+			t.Fatalf("%q: expected zulu %t, got %t", test.Raw, test.Zulu, zulu)
+		}
+	}
+}
+
+func TestPositionReport(t *testing.T) {
+	var (
+		ts  = time.Date(2020, time.March, 1, 9, 30, 15, 0, time.UTC)
+		pos = Position{Latitude: 49.058333, Longitude: -72.029167, Symbol: Symbol{'/', '>'}}
+		alt = Altitude(1234 * Foot)
+	)
+
+	var tests = []struct {
+		Report  PositionReport
+		Payload string
+	}{
+		{PositionReport{Position: pos, Comment: "Test"}, "!4903.50N/07201.75W>Test"},
+		{PositionReport{Position: pos, Messaging: true, Velocity: &Velocity{Course: 88, Speed: 36 * Knot}}, "=4903.50N/07201.75W>088/036"},
+		{PositionReport{Position: pos, Time: &ts, Messaging: true, Altitude: &alt}, "@010930z4903.50N/07201.75W>/A=001234"},
+		{PositionReport{Position: pos, Time: &ts, TimeFormat: TimeHMS}, "/093015h4903.50N/07201.75W>"},
+		{PositionReport{Position: pos, Time: &ts, TimeFormat: TimeDHMLocal}, "/010930/4903.50N/07201.75W>"},
+		{PositionReport{Position: pos, Time: &ts, TimeFormat: TimeMDHM}, "/010930z4903.50N/07201.75W>"},
+		{PositionReport{Position: pos, Object: &Object{Name: "LEADER"}, Time: &ts, TimeFormat: TimeDHMLocal}, ";LEADER   *010930z4903.50N/07201.75W>"},
+		{PositionReport{Position: pos, Object: &Object{Name: "AID #2", Item: true, Killed: true}}, ")AID #2_4903.50N/07201.75W>"},
+	}
+
+	for _, test := range tests {
+		payload := test.Report.String()
+		if payload != test.Payload {
+			t.Fatalf("expected %q, got %q", test.Payload, payload)
+		}
+
+		p, err := ParsePacket("N0CALL>APRS:" + payload)
+		if err != nil {
+			t.Fatalf("%q: %v", payload, err)
+		}
+		if d := testDistance(p.Position, &pos); d > 10 {
+			t.Fatalf("%q: position %s is %.fm off", payload, p.Position, d)
+		}
+		if p.Symbol != pos.Symbol {
+			t.Fatalf("%q: expected symbol %q, got %q", payload, pos.Symbol, p.Symbol)
+		}
+		if o := test.Report.Object; o != nil {
+			if p.Object == nil || *p.Object != *o {
+				t.Fatalf("%q: expected object %+v, got %+v", payload, o, p.Object)
+			}
+		}
+		if test.Report.Time != nil && test.Report.TimeFormat != TimeDHMLocal {
+			resolved, _, err := p.ResolveTime(ts.Add(time.Minute), nil)
+			if err != nil {
+				t.Fatalf("%q: %v", payload, err)
+			}
+			if want := ts.Truncate(time.Minute); test.Report.TimeFormat != TimeHMS && !resolved.Equal(want) {
+				t.Fatalf("%q: expected time %s, got %s", payload, want, resolved)
+			} else if test.Report.TimeFormat == TimeHMS && !resolved.Equal(ts) {
+				t.Fatalf("%q: expected time %s, got %s", payload, ts, resolved)
+			}
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	var ts = time.Date(2020, time.March, 1, 9, 30, 15, 0, time.FixedZone("CET", 3600))
+
+	var tests = []struct {
+		Format TimeFormat
+		Want   string
+	}{
+		{TimeDHMZulu, "010830z"},
+		{TimeDHMLocal, "010930/"},
+		{TimeHMS, "083015h"},
+		{TimeMDHM, "03010830"},
+	}
+
+	for _, test := range tests {
+		s := FormatTime(ts, test.Format)
+		if s != test.Want {
+			t.Fatalf("expected %q, got %q", test.Want, s)
+		}
+		resolved, _, err := ResolveTime(s, ts.Add(time.Hour), ts.Location())
+		if err != nil {
+			t.Fatalf("%q: %v", s, err)
+		}
+		if want := ts.Truncate(time.Minute); test.Format != TimeHMS && !resolved.Equal(want) {
+			t.Fatalf("%q: expected %s, got %s", s, want, resolved)
+		} else if test.Format == TimeHMS && !resolved.Equal(ts) {
+			t.Fatalf("%q: expected %s, got %s", s, ts, resolved)
+		}
+	}
+}
+
+func TestPacketDevice(t *testing.T) {
+	var tests = []struct {
+		Raw    string
+		Device Device
+		Found  bool
+	}{
+		{"N0CALL>APDR16,WIDE1-1:=4903.50N/07201.75W$", Device{Vendor: "Open Source", Model: "APRSdroid", Class: "app", OS: "Android", Version: "16"}, true},
+		{"N0CALL>APDW17:!4903.50N/07201.75W-", Device{Vendor: "WB2OSZ", Model: "DireWolf", Class: "software", Version: "17"}, true},
+		{"N0CALL>APK003:!4903.50N/07201.75W[", Device{Vendor: "Kenwood", Model: "TH-D72", Class: "ht"}, true},
+		{"N0CALL>APK004:!4903.50N/07201.75W[", Device{Vendor: "Kenwood", Model: "TH-D74", Class: "ht"}, true},
+		{"N0CALL>APZ123:!4903.50N/07201.75W[", Device{Model: "Experimental", Version: "123"}, true},
+		{"N0CALL>APRS:!4903.50N/07201.75W[", Device{}, false},
+		{"N0CALL>S32U6T:`d#f$lt>/`\"4T}Test_%", Device{Vendor: "Yaesu", Model: "FTM-400DR", Class: "rig"}, true},
+		{"N0CALL>S32U6T:`d#f$lt>/>Test^", Device{Vendor: "Kenwood", Model: "TH-D74", Class: "ht"}, true},
+		{"N0CALL>S32U6T:`d#f$lt>/]Test", Device{Vendor: "Kenwood", Model: "TM-D700", Class: "rig"}, true},
+		{"N0CALL>S32U6T:`d#f$lt>/", Device{}, false},
+	}
+
+	for _, test := range tests {
+		p, err := ParsePacket(test.Raw)
+		if err != nil {
+			t.Fatalf("%q: %v", test.Raw, err)
+		}
+		d, found := p.Device()
+		if found != test.Found || d != test.Device {
+			t.Fatalf("%q: expected %+v (%t), got %+v (%t)", test.Raw, test.Device, test.Found, d, found)
+		}
+	}
+
+	reg, err := Devices()
+	if err != nil {
+		t.Fatalf("embedded registry: %v", err)
+	}
+	if len(reg.Tocalls) == 0 || len(reg.MicE) == 0 {
+		t.Fatal("expected tocalls and Mic-E entries in the embedded registry")
+	}
+	if c, ok := reg.Class("ht"); !ok || c.Shown != "HT" {
+		t.Fatalf("expected class ht, got %+v", c)
+	}
+}
+
+func TestSymbol(t *testing.T) {
+	var tests = []struct {
+		Symbol  Symbol
+		Name    string
+		Code    string
+		Sprite  Sprite
+		Overlay bool
+	}{
+		{Symbol{'/', '>'}, "Car", "MV", Sprite{SpritePrimary, 13, 1}, false},
+		{Symbol{'\\', '#'}, "Overlay Digi", "OD", Sprite{SpriteAlternate, 2, 0}, false},
+		{Symbol{'S', '#'}, "SSn-N Digipeater", "ODS", Sprite{SpriteAlternate, 2, 0}, true},
+		{Symbol{'1', '#'}, "WIDE1-1 Digipeater", "OD1", Sprite{SpriteAlternate, 2, 0}, true},
+		{Symbol{'c', '#'}, "Overlay Digi", "OD2", Sprite{SpriteAlternate, 2, 0}, true},
+		{Symbol{'S', '0'}, "Staging Area", "A0S", Sprite{SpriteAlternate, 15, 0}, true},
+		{Symbol{'/', '~'}, "TNC Stream Switch", "J4", Sprite{SpritePrimary, 13, 5}, false},
+	}
+
+	for _, test := range tests {
+		if s := test.Symbol.String(); s != test.Name {
+			t.Fatalf("%q: expected name %q, got %q", test.Symbol[:], test.Name, s)
+		}
+		code, err := test.Symbol.Code()
+		if err != nil {
+			t.Fatalf("%q: %v", test.Symbol[:], err)
+		}
+		if code != test.Code {
+			t.Fatalf("%q: expected code %q, got %q", test.Symbol[:], test.Code, code)
+		}
+		if s, ok := SymbolByCode(code); !ok || s.String() != test.Name {
+			t.Fatalf("%q: code %q returned %q", test.Symbol[:], code, s[:])
+		}
+		if sp, ok := test.Symbol.Sprite(); !ok || sp != test.Sprite {
+			t.Fatalf("%q: expected sprite %+v, got %+v", test.Symbol[:], test.Sprite, sp)
+		}
+		if _, ok := test.Symbol.OverlaySprite(); ok != test.Overlay {
+			t.Fatalf("%q: expected overlay %t, got %t", test.Symbol[:], test.Overlay, ok)
+		}
+	}
+
+	if s, ok := SymbolByName("ssn-n digipeater"); !ok || s != (Symbol{'S', '#'}) {
+		t.Fatalf("expected SSn-N digipeater, got %q", s[:])
+	}
+	if s, ok := SymbolByName("Ambulance"); !ok || s != (Symbol{'/', 'a'}) {
+		t.Fatalf("expected ambulance, got %q", s[:])
+	}
+	if s, ok := SymbolFromSSID(9); !ok || s != (Symbol{'/', '>'}) {
+		t.Fatalf("expected car, got %q", s[:])
+	}
+	if _, ok := SymbolFromSSID(0); ok {
+		t.Fatal("expected no symbol for SSID 0")
+	}
+	if sp, ok := (Symbol{'5', '^'}).OverlaySprite(); !ok || sp != (Sprite{SpriteOverlay, 4, 1}) {
+		t.Fatalf("expected overlay sprite, got %+v", sp)
+	}
+}
+
+func TestPacketJSON(t *testing.T) {
+	var tests = []string{
+		"N0CALL>APRS,WIDE1-1,WIDE2-1*:!4903.50N/07201.75W-Test /A=001234",
+		"N0CALL>APRS:!49  .  N/072  .  W-",
+		"N0CALL>APRS:@092345z4903.50N/07201.75W>088/036",
+		"N0CALL>APRS:=4903.50N/07201.75W#PHG5132",
+		"N0CALL>APRS:/234517h4903.50N/07201.75W>DFS2360",
+		"N0CALL>APRS:@092345z4903.50N/07201.75W_090/000g005t077r000p000P000h50b09900",
+		"N0CALL>APRS:>IO91SX/G Test^JK",
+		"N0CALL>APRS::PA1ABC   :Hello{12",
+		"N0CALL>APRS:?APRS? 34.02,-117.15,0200",
+		"N0CALL>APRS:<IGATE,MSG_CNT=30,LOC_CNT=12",
+		"N0CALL>APRS,WIDE1-1:}PA1ABC>APDR16,TCPIP,N0CALL*:=4903.50N/07201.75W$",
+		"N0CALL>APRS:$GPRMC,063909,A,3349.4302,N,11700.3721,W,43.022,89.3,291099,13.6,E*52",
+		"N0CALL>APRS:;LEADER   _092345z4903.50N/07201.75W>Test",
+		"N0CALL>APRS:)AID #2!4903.50N/07201.75W>",
+		"N0CALL>APRS:!4903.50N/07201.75W>Test!W5!",
+		"N0CALL>S32U6T:`d#f$lt>/",
+	}
+
+	for _, raw := range tests {
+		p, err := ParsePacket(raw)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+
+		var q Packet
+		if err = json.Unmarshal(b, &q); err != nil {
+			t.Fatalf("%q: %v\n%s", raw, err, b)
+		}
+		p.data, p.dataOffset = "", 0
+		if p.ThirdParty != nil {
+			p.ThirdParty.data, p.ThirdParty.dataOffset = "", 0
+		}
+		if !reflect.DeepEqual(p, q) {
+			t.Fatalf("%q: round trip mismatch\n%s\n%#v\n%#v", raw, b, p, q)
+		}
+	}
+
+	var p Packet
+	err := json.Unmarshal([]byte(`{"src":"N0CALL-9","dst":"APRS","path":["WIDE1-1","WIDE2-2*"],"payload":">Hello","symbol":"/>"}`), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := p.String(); s != "N0CALL-9>APRS,WIDE1-1,WIDE2-2*:>Hello" {
+		t.Fatalf("unexpected TNC2 %q", s)
+	}
+	if p.Symbol != (Symbol{'/', '>'}) {
+		t.Fatalf("unexpected symbol %q", p.Symbol[:])
+	}
+
+	var m map[string]interface{}
+	b, _ := json.Marshal(p)
+	if err = json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["type"] != ">" || m["symbol"] != "/>" {
+		t.Fatalf("unexpected JSON %s", b)
+	}
+}
+
+func TestPacketJSONBinary(t *testing.T) {
+	var tests = []string{
+		"N0CALL>APRS:>caf\xe9",
+		"N0CALL>APRS:>caf\u00e9",
+		"N0CALL>APRS:\xe9\x80\xff",
+		"N0CALL>APRS:\u00e9 test",
+	}
+
+	for _, raw := range tests {
+		p, err := ParsePacketMode(raw, Lenient)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("%q: %v", raw, err)
+		}
+
+		var q Packet
+		if err = json.Unmarshal(b, &q); err != nil {
+			t.Fatalf("%q: %v\n%s", raw, err, b)
+		}
+		if q.Raw != raw || q.Payload != p.Payload {
+			t.Fatalf("%q: round trip mismatch %q %q\n%s", raw, q.Raw, q.Payload, b)
+		}
+
+		var m map[string]interface{}
+		if err = json.Unmarshal(b, &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["type"] != string(rune(p.Payload.Type())) {
+			t.Errorf("%q: unexpected type %q", raw, m["type"])
+		}
+		var dt DataType
+		if b, err = json.Marshal(p.Payload.Type()); err != nil {
+			t.Fatal(err)
+		}
+		if err = json.Unmarshal(b, &dt); err != nil || dt != p.Payload.Type() {
+			t.Errorf("%q: type round trip %q: %v", raw, dt, err)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	long := "N0CALL>APRS:>" + strings.Repeat("x", MaxLineLength)
+	input := "# aprsc 2.1.4\r\n" +
+		"N0CALL>APRS,TCPIP*:>status\r\n" +
+		"\n" +
+		long + "\n" +
+		"invalid\n" +
+		"N0CALL>APRS:>caf\xe9\n" +
+		"N0CALL>APRS:>last"
+
+	d := NewDecoder(strings.NewReader(input))
+	d.Latin1 = true
+	var tests = []struct {
+		Line int
+		Raw  string
+		Err  error
+	}{
+		{2, "N0CALL>APRS,TCPIP*:>status", nil},
+		{4, long[:MaxLineLength], ErrLineTooLong},
+		{5, "invalid", ErrInvalidPacket},
+		{6, "N0CALL>APRS:>café", nil},
+		{7, "N0CALL>APRS:>last", nil},
+	}
+	for _, test := range tests {
+		p, err := d.Decode()
+		if test.Err == nil && err != nil {
+			t.Fatalf("line %d: unexpected error %v", test.Line, err)
+		} else if test.Err != nil {
+			var lerr *LineError
+			if !errors.As(err, &lerr) || !errors.Is(err, test.Err) {
+				t.Fatalf("line %d: expected %v, got %v", test.Line, test.Err, err)
+			}
+			if lerr.Raw != test.Raw {
+				t.Errorf("line %d: expected raw %q, got %q", test.Line, test.Raw, lerr.Raw)
+			}
+		}
+		if d.Line() != test.Line {
+			t.Errorf("expected line %d, got %d", test.Line, d.Line())
+		}
+		if p.Raw != test.Raw {
+			t.Errorf("line %d: expected raw %q, got %q", test.Line, test.Raw, p.Raw)
+		}
+	}
+	if _, err := d.Decode(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	var b strings.Builder
+	e := NewEncoder(&b)
+	p, _ := ParsePacket("N0CALL>APRS,WIDE1-1:>status")
+	if err := e.Encode(p); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "N0CALL>APRS,WIDE1-1:>status\r\n" {
+		t.Errorf("expected CRLF terminated line, got %q", b.String())
+	}
+	p.Payload = ">two\r\nlines"
+	if err := e.Encode(p); err != ErrInvalidPacket {
+		t.Errorf("expected %v, got %v", ErrInvalidPacket, err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var tests = []struct {
+		Raw      string
+		Offset   int
+		Field    string
+		DataType DataType
+		Err      error
+	}{
+		{"N0CALL>APRS", 11, "header", 0, ErrInvalidPacket},
+		{"N0CALL-99>APRS:>test", 0, "source", 0, ErrAddressInvalid},
+		{"N0CALL>APRS:!4903.50X/07201.75W-", 20, "latitude", '!', ErrInvalidPosition},
+		{"N0CALL>APRS:=4903.50N/19001.75W-", 22, "longitude", '=', ErrInvalidPosition},
+		{"N0CALL>APRS:!/5L!!<|e7>7P[", 19, "longitude", '!', ErrBase91Decode},
+		{"N0CALL>APRS:`(_fn\"Oj/", 7, "destination", '`', ErrInvalidPosition},
+	}
+	for _, test := range tests {
+		_, err := ParsePacket(test.Raw)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("%q: expected *ParseError, got %v", test.Raw, err)
+		}
+		if perr.Offset != test.Offset || perr.Field != test.Field || perr.DataType != test.DataType || !perr.Fatal() {
+			t.Errorf("%q: unexpected %s at offset %d (%q, fatal %t)", test.Raw, perr.Field, perr.Offset, perr.DataType, perr.Fatal())
+		}
+		if !errors.Is(err, test.Err) {
+			t.Errorf("%q: expected %v, got %v", test.Raw, test.Err, err)
+		}
+	}
+
+	p, err := ParsePacket("N0CALL>APRS:!4903.50N/07201.75W-RNG12ab Test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Diagnostics) != 1 || p.Diagnostics[0].Field != "range" || p.Diagnostics[0].Offset != 35 || p.Diagnostics[0].Fatal() {
+		t.Fatalf("expected range warning at offset 35, got %v", p.Diagnostics)
+	}
+	if p.Position == nil || p.Comment != "RNG12ab Test" {
+		t.Errorf("expected position and comment, got %v and %q", p.Position, p.Comment)
+	}
+}
+
+func TestParseLenient(t *testing.T) {
+	for _, raw := range []string{
+		"N0CALL>APRS::PD0MZ:Hello",
+		"N0CALL>APRS:{Q1qwerty",
+	} {
+		if _, err := ParsePacketMode(raw, Strict); err == nil {
+			t.Fatalf("%q: expected error in strict mode", raw)
+		}
+		p, err := ParsePacketMode(raw, Lenient)
+		if err != nil {
+			t.Fatalf("%q: unexpected error in lenient mode: %v", raw, err)
+		}
+		if p.Src == nil || p.Dst == nil || p.Payload != Payload(raw[12:]) || p.Payload.Type() != DataType(raw[12]) {
+			t.Errorf("%q: expected header and payload, got %+v", raw, p)
+		}
+		if len(p.Diagnostics) != 1 || !p.Diagnostics[0].Fatal() || p.Diagnostics[0].DataType != DataType(raw[12]) {
+			t.Fatalf("%q: expected a fatal diagnostic, got %v", raw, p.Diagnostics)
+		}
+
+		b, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var q Packet
+		if err = json.Unmarshal(b, &q); err != nil {
+			t.Fatal(err)
+		}
+		if len(q.Diagnostics) != 1 || q.Diagnostics[0].Error() != p.Diagnostics[0].Error() {
+			t.Errorf("%q: diagnostics round trip mismatch\n%s", raw, b)
+		}
+	}
+
+	if _, err := ParsePacketMode("N0CALL:>no destination", Lenient); err == nil {
+		t.Error("expected header error in lenient mode")
+	}
+
+	d := NewDecoder(strings.NewReader("N0CALL>APRS::PD0MZ:Hello\r\nN0CALL-99>APRS:>test\r\n"))
+	d.Mode = Lenient
+	if p, err := d.Decode(); err != nil || len(p.Diagnostics) != 1 {
+		t.Errorf("expected packet with diagnostics, got %v", err)
+	}
+	if _, err := d.Decode(); !errors.Is(err, ErrAddressInvalid) {
+		t.Errorf("expected %v, got %v", ErrAddressInvalid, err)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	var tests = []struct {
+		Raw    string
+		Offset int
+		Field  string
+	}{
+		{"N0CALL>APRS:!", 13, "position"},
+		{"N0CALL>APRS:!4903.50N", 21, "position"},
+		{"N0CALL>APRS:=", 13, "position"},
+		{"N0CALL>APRS:=/5L!", 17, "position"},
+		{"N0CALL>APRS:/", 13, "timestamp"},
+		{"N0CALL>APRS:/092345z", 20, "position"},
+		{"N0CALL>APRS:/234517h", 20, "position"},
+		{"N0CALL>APRS:@092345/", 20, "position"},
+		{"N0CALL>APRS:/10092345", 21, "position"},
+		{"N0CALL>APRS:/092345x4903.50N/07201.75W-", 19, "timestamp"},
+		{"N0CALL>APRS:}A>B:!", 18, "position"},
+		{"N0CALL>APRS:}A>B:/092345z", 25, "position"},
+	}
+
+	for _, test := range tests {
+		_, err := ParsePacketMode(test.Raw, Strict)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: expected *ParseError in strict mode, got %v", test.Raw, err)
+			continue
+		}
+		if perr.Offset != test.Offset || perr.Field != test.Field || !perr.Fatal() {
+			t.Errorf("%q: expected fatal %s error at offset %d, got %v", test.Raw, test.Field, test.Offset, perr)
+		}
+
+		p, err := ParsePacketMode(test.Raw, Lenient)
+		if err != nil {
+			t.Errorf("%q: unexpected error in lenient mode: %v", test.Raw, err)
+			continue
+		}
+		offset := test.Offset
+		if p.ThirdParty != nil {
+			// Diagnostics of third-party traffic are relative to its own raw packet.
+			p, offset = *p.ThirdParty, offset-strings.IndexByte(test.Raw, '}')-1
+		}
+		if len(p.Diagnostics) != 1 || p.Diagnostics[0].Offset != offset || !p.Diagnostics[0].Fatal() {
+			t.Errorf("%q: expected a fatal diagnostic at offset %d, got %v", test.Raw, offset, p.Diagnostics)
+		}
+		if p.Position != nil {
+			t.Errorf("%q: unexpected position %v", test.Raw, p.Position)
+		}
+	}
+}
+
+func FuzzParsePacket(f *testing.F) {
+	for _, raw := range []string{
+		"N0CALL>APRS,WIDE1-1,WIDE2-1*:!4903.50N/07201.75W-Test /A=001234",
+		"N0CALL>APRS:=/5L!!<*e7>7P[",
+		"N0CALL>APRS:@092345z4903.50N/07201.75W>088/036",
+		"N0CALL>APRS:/234517h4903.50N/07201.75W>DFS2360",
+		"N0CALL>APRS:/10092345/5L!!<*e7>7P[",
+		"N0CALL>APRS:;LEADER   _092345z4903.50N/07201.75W>Test",
+		"N0CALL>APRS:)AID #2!4903.50N/07201.75W>",
+		"N0CALL>APRS,WIDE1-1:}PA1ABC>APDR16,TCPIP,N0CALL*:=4903.50N/07201.75W$",
+		"N0CALL>S32U6T:`d#f$lt>/",
+		"N0CALL>APRS:$GPRMC,063909,A,3349.4302,N,11700.3721,W,43.022,89.3,291099,13.6,E*52",
+		"N0CALL>APRS:>IO91SX/G Test^JK",
+		"N0CALL>APRS::PA1ABC   :Hello{12",
+		"N0CALL>APRS:?APRS? 34.02,-117.15,0200",
+		"N0CALL>APRS:!",
+		"N0CALL>APRS:/092345z",
+	} {
+		f.Add(raw)
+	}
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		for _, mode := range []ParseMode{Strict, Lenient} {
+			p, err := ParsePacketMode(raw, mode)
+			if err != nil {
+				var perr *ParseError
+				if errors.As(err, &perr) && (perr.Offset < 0 || perr.Offset > len(raw)) {
+					t.Errorf("%q: offset %d out of range", raw, perr.Offset)
+				}
+				continue
+			}
+			_ = p.String()
+			for _, d := range p.Diagnostics {
+				if d.Offset < 0 || d.Offset > len(raw) {
+					t.Errorf("%q: diagnostic offset %d out of range", raw, d.Offset)
+				}
+			}
+		}
+	})
+}
+
+func TestPacketStringZero(t *testing.T) {
+	var p Packet
+	if s := p.String(); s != "" {
+		t.Errorf("expected empty string, got %q", s)
+	}
+
+	p, err := ParsePacket("N0CALL:>no destination")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if s := fmt.Sprint(p); s != p.Raw {
+		t.Errorf("expected %q, got %q", p.Raw, s)
+	}
+}
@@ -1,8 +1,8 @@
 package aprs
 
 import (
-	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -10,9 +10,12 @@ import (
 )
 
 var (
-	// Position ambiguity replacement
+	// Position ambiguity replacement, latitude and longitude digits
 	disambiguation = []int{2, 3, 5, 6, 12, 13, 15, 16}
 
+	// Position ambiguity box size in minutes, per ambiguity level
+	ambiguityMinutes = []float64{0, 0.1, 1, 10, 60}
+
 	miceCodes = map[rune]map[int]string{
 		'0': map[int]string{0: "0", 1: "0", 2: "S", 3: "0", 4: "E"},
 		'1': map[int]string{0: "1", 1: "0", 2: "S", 3: "0", 4: "E"},
@@ -78,9 +81,11 @@ const (
 type Position struct {
 	Latitude   float64 // Degrees
 	Longitude  float64 // Degrees
-	Ambiguity  int
+	Ambiguity  int     // Ambiguity level, 0-4
 	Symbol     Symbol
 	Compressed bool
+	Datum      byte    // DAO datum, 0 if none
+	Precision  float64 // Meters, 0 if unknown
 }
 
 func (pos Position) String() string {
@@ -90,19 +95,97 @@ func (pos Position) String() string {
 	return fmt.Sprintf("{%f, %f}, ambiguity=%d", pos.Latitude, pos.Longitude, pos.Ambiguity)
 }
 
+// ambiguity returns the size of the ambiguity box in degrees.
+func (pos Position) ambiguity() float64 {
+	if pos.Ambiguity <= 0 || pos.Compressed {
+		return 0
+	}
+	if pos.Ambiguity >= len(ambiguityMinutes) {
+		return ambiguityMinutes[len(ambiguityMinutes)-1] / 60
+	}
+	return ambiguityMinutes[pos.Ambiguity] / 60
+}
+
+// Bounds returns the area implied by the position ambiguity. Ambiguous
+// positions are parsed as the corner of this area closest to the equator and
+// the prime meridian.
+func (pos Position) Bounds() Box {
+	d := pos.ambiguity()
+	b := Box{
+		South: pos.Latitude,
+		West:  pos.Longitude,
+		North: pos.Latitude,
+		East:  pos.Longitude,
+	}
+	if pos.Latitude < 0 {
+		b.South -= d
+	} else {
+		b.North += d
+	}
+	if pos.Longitude < 0 {
+		b.West -= d
+	} else {
+		b.East += d
+	}
+	return b
+}
+
+// Center returns the centre of the area implied by the position ambiguity,
+// for positions without ambiguity this is the position itself.
+func (pos Position) Center() Position {
+	if pos.ambiguity() == 0 {
+		return pos
+	}
+	b := pos.Bounds()
+	c := pos
+	c.Latitude = (b.South + b.North) / 2
+	c.Longitude = (b.West + b.East) / 2
+	return c
+}
+
+// AmbiguityRadius returns the distance from the centre to the corners of the
+// area implied by the position ambiguity.
+func (pos Position) AmbiguityRadius() Distance {
+	if pos.ambiguity() == 0 {
+		return 0
+	}
+	b := pos.Bounds()
+	return pos.Center().DistanceTo(Position{Latitude: b.North, Longitude: b.East})
+}
+
+// Box is a geographic area bounded by latitudes and longitudes in degrees.
+type Box struct {
+	South float64
+	West  float64
+	North float64
+	East  float64
+}
+
+// Contains returns true if the position is inside the box.
+func (b Box) Contains(pos Position) bool {
+	return pos.Latitude >= b.South && pos.Latitude <= b.North &&
+		pos.Longitude >= b.West && pos.Longitude <= b.East
+}
+
 func ParseUncompressedPosition(s string) (Position, string, error) {
 	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 8, page 32 (42 in PDF)
 
 	pos := Position{}
 
-	if len(s) < 18 {
-		return pos, "", errors.New("aprs: invalid position")
+	if len(s) < 19 {
+		return pos, "", &ParseError{Offset: len(s), Field: "position", Err: ErrInvalidPosition}
 	}
 
+	// The ambiguity is the number of spaces in the latitude, the longitude
+	// is ambiguous to the same degree.
 	b := []byte(s)
-	for _, p := range disambiguation {
+	for _, p := range disambiguation[:4] {
 		if b[p] == ' ' {
 			pos.Ambiguity++
+		}
+	}
+	for i, p := range disambiguation {
+		if b[p] == ' ' || i%4 >= 4-pos.Ambiguity {
 			b[p] = '0'
 		}
 	}
@@ -117,24 +200,24 @@ func ParseUncompressedPosition(s string) (Position, string, error) {
 	)
 
 	if latDeg, err = strconv.ParseUint(s[0:2], 10, 8); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 0, Field: "latitude", Err: err}
 	}
 	if latMin, err = strconv.ParseUint(s[2:4], 10, 8); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 2, Field: "latitude", Err: err}
 	}
 	if latMinFrag, err = strconv.ParseUint(s[5:7], 10, 8); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 5, Field: "latitude", Err: err}
 	}
 	latHemi = s[7]
 	pos.Symbol[0] = s[8]
 	if lngDeg, err = strconv.ParseUint(s[9:12], 10, 8); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 9, Field: "longitude", Err: err}
 	}
 	if lngMin, err = strconv.ParseUint(s[12:14], 10, 8); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 12, Field: "longitude", Err: err}
 	}
 	if lngMinFrag, err = strconv.ParseUint(s[15:17], 10, 8); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 15, Field: "longitude", Err: err}
 	}
 	lngHemi = s[17]
 	pos.Symbol[1] = s[18]
@@ -142,22 +225,30 @@ func ParseUncompressedPosition(s string) (Position, string, error) {
 	if latHemi == 'S' || latHemi == 's' {
 		isSouth = true
 	} else if latHemi != 'N' && latHemi != 'n' {
-		return pos, "", errors.New("aprs: invalid position")
+		return pos, "", &ParseError{Offset: 7, Field: "latitude", Err: ErrInvalidPosition}
 	}
 
 	if lngHemi == 'W' || lngHemi == 'w' {
 		isWest = true
 	} else if lngHemi != 'E' && lngHemi != 'e' {
-		return pos, "", errors.New("aprs: invalid position")
+		return pos, "", &ParseError{Offset: 17, Field: "longitude", Err: ErrInvalidPosition}
 	}
 
-	if latDeg > 89 || lngDeg > 179 {
-		return pos, "", errors.New("aprs: invalid position")
+	if latDeg > 89 {
+		return pos, "", &ParseError{Offset: 0, Field: "latitude", Err: ErrInvalidPosition}
+	}
+	if lngDeg > 179 {
+		return pos, "", &ParseError{Offset: 9, Field: "longitude", Err: ErrInvalidPosition}
 	}
 
 	pos.Latitude = float64(latDeg) + float64(latMin)/60.0 + float64(latMinFrag)/6000.0
 	pos.Longitude = float64(lngDeg) + float64(lngMin)/60.0 + float64(lngMinFrag)/6000.0
 
+	pos.Precision = precisionUncompressed
+	if pos.Ambiguity > 0 {
+		pos.Precision = precisionMinute * ambiguityMinutes[pos.Ambiguity]
+	}
+
 	if isSouth {
 		pos.Latitude = 0.0 - pos.Latitude
 	}
@@ -165,10 +256,6 @@ func ParseUncompressedPosition(s string) (Position, string, error) {
 		pos.Longitude = 0.0 - pos.Longitude
 	}
 
-	if pos.Symbol[1] >= 'a' || pos.Symbol[1] <= 'k' {
-		pos.Symbol[1] -= 32
-	}
-
 	if len(s) > 19 {
 		return pos, s[19:], nil
 	}
@@ -181,39 +268,54 @@ func ParseCompressedPosition(s string) (Position, string, error) {
 	pos := Position{}
 
 	if len(s) < 10 {
-		return pos, "", errors.New("aprs: invalid position")
+		return pos, "", &ParseError{Offset: len(s), Field: "position", Err: ErrInvalidPosition}
 	}
 
 	// Base-91 check
-	for _, c := range s[1:9] {
+	for i, c := range s[1:9] {
 		if c < 0x21 || c > 0x7b {
-			return pos, "", errors.New("aprs: invalid position")
+			field := "latitude"
+			if i >= 4 {
+				field = "longitude"
+			}
+			return pos, "", &ParseError{Offset: 1 + i, Field: field, Err: ErrBase91Decode}
 		}
 	}
 
 	var err error
 	var lat, lng int
 	if lat, err = base91Decode(s[1:5]); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 1, Field: "latitude", Err: err}
 	}
 	if lng, err = base91Decode(s[5:9]); err != nil {
-		return pos, "", err
+		return pos, "", &ParseError{Offset: 5, Field: "longitude", Err: err}
 	}
 
 	pos.Latitude = 90.0 - float64(lat)/380926.0
 	pos.Longitude = -180.0 + float64(lng)/190463.0
 	pos.Compressed = true
+	pos.Precision = precisionCompressed
 
 	return pos, s[10:], nil
 }
 
+// ParseMicE parses a Mic-E position from the payload and the destination
+// call. Offsets of errors in the "destination" field are relative to dest.
 func ParseMicE(s, dest string) (Position, error) {
 	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 10, page 42 in PDF
 
 	pos := Position{}
 
-	if len(s) < 9 || len(dest) != 6 {
-		return pos, errors.New("aprs: invalid MicE position")
+	if len(dest) != 6 {
+		return pos, &ParseError{Offset: 0, Field: "destination", Err: ErrInvalidPosition}
+	}
+	if len(s) < 9 {
+		return pos, &ParseError{Offset: len(s), Field: "position", Err: ErrInvalidPosition}
+	}
+	for i := 0; i < len(dest); i++ {
+		if _, ok := miceCodes[rune(dest[i])]; !ok {
+			return pos, &ParseError{Offset: i, Field: "destination", Err: ErrInvalidPosition}
+		}
 	}
 
 	ns := miceCodes[rune(dest[3])][2]
@@ -223,13 +325,13 @@ func ParseMicE(s, dest string) (Position, error) {
 	latF = strings.Trim(latF, ". ")
 	latD, err := strconv.ParseFloat(latF, 64)
 	if err != nil {
-		return pos, errors.New("aprs: invalid position")
+		return pos, &ParseError{Offset: 0, Field: "destination", Err: ErrInvalidPosition}
 	}
 	lonF := fmt.Sprintf("%s%s.%s%s", miceCodes[rune(dest[2])][0], miceCodes[rune(dest[3])][0], miceCodes[rune(dest[4])][0], miceCodes[rune(dest[5])][0])
 	lonF = strings.Trim(lonF, ". ")
 	latM, err := strconv.ParseFloat(lonF, 64)
 	if err != nil {
-		return pos, errors.New("aprs: invalid position")
+		return pos, &ParseError{Offset: 2, Field: "destination", Err: ErrInvalidPosition}
 	}
 	if latM != 0 {
 		latD += latM / 60
@@ -268,6 +370,7 @@ func ParseMicE(s, dest string) (Position, error) {
 
 	pos.Latitude = latD
 	pos.Longitude = lonD
+	pos.Precision = precisionUncompressed
 
 	return pos, nil
 }
@@ -275,26 +378,84 @@ func ParseMicE(s, dest string) (Position, error) {
 func ParsePositionGrid(s string) (Position, string, error) {
 	var o int
 	for o = 0; o < len(s); o++ {
-		if strings.IndexByte(gridChars, s[o]) < 0 {
+		if strings.IndexByte(gridChars, upper(s[o])) < 0 {
 			break
 		}
 	}
 
 	pos := Position{}
-	if o == 2 || o == 4 || o == 6 || o == 8 {
-		p, err := maidenhead.ParseLocator(s[:o])
-		if err != nil {
-			return pos, "", err
+	if o < 2 || o > 10 || o%2 != 0 {
+		return pos, "", &ParseError{Offset: o, Field: "locator", Err: ErrInvalidPosition}
+	}
+	p, err := maidenhead.ParseLocator(strings.ToUpper(s[:o]))
+	if err != nil {
+		return pos, "", &ParseError{Offset: 0, Field: "locator", Err: err}
+	}
+	pos.Latitude = p.Latitude
+	pos.Longitude = p.Longitude
+
+	return pos, strings.TrimPrefix(s[o:], "]"), nil
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 32
+	}
+	return c
+}
+
+// Locator returns the Maidenhead locator of the position with the requested
+// number of characters (2, 4, 6, 8 or 10).
+func (pos Position) Locator(precision int) (string, error) {
+	if precision < 2 || precision > 10 || precision%2 != 0 {
+		return "", fmt.Errorf("aprs: invalid locator precision %d", precision)
+	}
+	if pos.Latitude < -90 || pos.Latitude > 90 || pos.Longitude < -180 || pos.Longitude > 180 {
+		return "", ErrInvalidPosition
+	}
+
+	// Nudge the coordinates by a fraction of the smallest cell, so that the
+	// corners of cells don't round down to the neighbouring cell.
+	var (
+		lat  = math.Min(pos.Latitude+90+1e-9, 180-1e-9)
+		lng  = math.Min(pos.Longitude+180+1e-9, 360-1e-9)
+		latW = 10.0 // Degrees per field
+		lngW = 20.0
+		b    = make([]byte, precision)
+	)
+	for i := 0; i < precision; i += 2 {
+		var base byte
+		switch {
+		case i == 0:
+			base = 'A'
+		case i%4 == 0:
+			base = 'a'
+		default:
+			base = '0'
+		}
+		b[i] = base + byte(lng/lngW)
+		b[i+1] = base + byte(lat/latW)
+		lng = math.Mod(lng, lngW)
+		lat = math.Mod(lat, latW)
+
+		// Fields are split in 10 squares, squares in 24 subsquares, etc.
+		if i%4 == 0 {
+			lngW, latW = lngW/10, latW/10
+		} else {
+			lngW, latW = lngW/24, latW/24
 		}
-		pos.Latitude = p.Latitude
-		pos.Longitude = p.Longitude
 	}
+	return string(b), nil
+}
 
-	var txt string
-	if o < len(s) {
-		txt = s[o+1:]
+// FormatGridBeacon formats a Maidenhead grid locator beacon, including the
+// leading '['.
+func FormatGridBeacon(pos Position, precision int, comment string) (string, error) {
+	l, err := pos.Locator(precision)
+	if err != nil {
+		return "", err
 	}
-	return pos, txt, nil
+	return "[" + strings.ToUpper(l) + "]" + comment, nil
 }
 
 func ParsePosition(s string, compressed bool) (Position, string, error) {
@@ -311,3 +472,72 @@ func ParsePositionBoth(s string) (Position, string, error) {
 	}
 	return pos, txt, err
 }
+
+// FormatUncompressedPosition formats the position as an uncompressed
+// latitude, symbol table, longitude and symbol code string. If the position
+// has a datum, the extra precision is to be sent using FormatDAO.
+func FormatUncompressedPosition(pos Position) string {
+	var (
+		latHemi, lngHemi = byte('N'), byte('E')
+		lat, lng         = pos.Latitude, pos.Longitude
+	)
+	if lat < 0 {
+		latHemi, lat = 'S', -lat
+	}
+	if lng < 0 {
+		lngHemi, lng = 'W', -lng
+	}
+
+	// Work in hundredths of minutes to avoid rounding up to 60 minutes.
+	latH := int(math.Round(lat * 6000))
+	lngH := int(math.Round(lng * 6000))
+	if pos.Ambiguity > 0 {
+		// Truncate, the digits are replaced by spaces.
+		latH = int(lat * 6000)
+		lngH = int(lng * 6000)
+	} else if pos.Datum != 0 {
+		// Truncate, the remainder is carried by the DAO extension.
+		steps := DAO{Datum: pos.Datum}.steps()
+		latH, _ = daoSplit(lat, steps)
+		lngH, _ = daoSplit(lng, steps)
+	}
+
+	table, code := pos.Symbol[0], pos.Symbol[1]
+	if table == 0 {
+		table = '/'
+	}
+	if code == 0 {
+		code = '/'
+	}
+
+	b := []byte(fmt.Sprintf("%02d%02d.%02d%c%c%03d%02d.%02d%c%c",
+		latH/6000, (latH/100)%60, latH%100, latHemi, table,
+		lngH/6000, (lngH/100)%60, lngH%100, lngHemi, code))
+	for i, p := range disambiguation {
+		if i%4 >= 4-pos.Ambiguity {
+			b[p] = ' '
+		}
+	}
+	return string(b)
+}
+
+// FormatCompressedPosition formats the position as a compressed symbol table,
+// latitude, longitude and symbol code string, followed by csT bytes without
+// course, speed or altitude. Compressed positions can not express ambiguity,
+// for ambiguous positions the centre of the ambiguity area is used.
+func FormatCompressedPosition(pos Position) string {
+	pos = pos.Center()
+
+	table, code := pos.Symbol[0], pos.Symbol[1]
+	if table == 0 {
+		table = '/'
+	}
+	if code == 0 {
+		code = '/'
+	}
+
+	lat := int(math.Round(380926 * (90 - pos.Latitude)))
+	lng := int(math.Round(190463 * (180 + pos.Longitude)))
+
+	return string(table) + base91Encode(lat, 4) + base91Encode(lng, 4) + string(code) + " sT"
+}
@@ -0,0 +1,253 @@
+package aprs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidQuery signals a corrupted APRS query.
+	ErrInvalidQuery = errors.New("aprs: invalid query")
+)
+
+// General query types.
+const (
+	QueryAPRS  = "APRS"
+	QueryIGate = "IGATE"
+	QueryWX    = "WX"
+)
+
+// Directed query types.
+const (
+	QueryDirect   = "APRSD" // Stations heard direct
+	QueryHeard    = "APRSH" // Packets heard from a station
+	QueryMessages = "APRSM" // Outstanding messages
+	QueryObjects  = "APRSO" // Objects
+	QueryPosition = "APRSP" // Position
+	QueryStatus   = "APRSS" // Status
+	QueryTrace    = "APRST" // Trace route
+	QueryPing     = "PING"  // Ping, answered like a trace route
+)
+
+// Query is a decoded general or directed query.
+type Query struct {
+	Type      string
+	Directed  bool       // Query was received in a message
+	Target    string     // Callsign argument of the query, if any
+	Footprint *Footprint // Area the general query is targeted at, if any
+}
+
+// Footprint is the target area of a general query.
+type Footprint struct {
+	Latitude  float64 // Degrees
+	Longitude float64 // Degrees
+	Radius    Distance
+}
+
+// Contains returns true if the position is within the footprint, ambiguous
+// positions are within the footprint if their area overlaps it.
+func (f Footprint) Contains(pos Position) bool {
+	d := pos.Center().DistanceTo(Position{Latitude: f.Latitude, Longitude: f.Longitude})
+	return d-pos.AmbiguityRadius() <= f.Radius
+}
+
+// ParseQuery parses a general query (without the leading '?').
+func ParseQuery(s string) (Query, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 15, page 77 (87 in PDF)
+
+	q := Query{}
+
+	i := strings.IndexByte(s, '?')
+	if i <= 0 {
+		return q, ErrInvalidQuery
+	}
+	q.Type = s[:i]
+
+	// Optional target footprint
+	if f := strings.TrimSpace(s[i+1:]); f != "" {
+		p := strings.Split(f, ",")
+		if len(p) != 3 {
+			return q, ErrInvalidQuery
+		}
+		var (
+			fp  Footprint
+			err error
+		)
+		if fp.Latitude, err = strconv.ParseFloat(strings.TrimSpace(p[0]), 64); err != nil {
+			return q, err
+		}
+		if fp.Longitude, err = strconv.ParseFloat(strings.TrimSpace(p[1]), 64); err != nil {
+			return q, err
+		}
+		var r float64
+		if r, err = strconv.ParseFloat(strings.TrimSpace(p[2]), 64); err != nil {
+			return q, err
+		}
+		fp.Radius = Distance(r) * Mile // Footprint radius is in miles
+		q.Footprint = &fp
+	}
+
+	return q, nil
+}
+
+// ParseDirectedQuery parses a directed query from a message text.
+func ParseDirectedQuery(s string) (Query, error) {
+	q := Query{Directed: true}
+
+	if len(s) < 2 || s[0] != '?' {
+		return q, ErrInvalidQuery
+	}
+	s = s[1:]
+
+	if i := strings.IndexByte(s, '?'); i > 0 {
+		// ?PING?, ?IGATE?, ?WX?
+		q.Type = s[:i]
+		q.Target = strings.TrimSpace(s[i+1:])
+		return q, nil
+	}
+
+	if len(s) < 5 || !strings.HasPrefix(s, "APRS") {
+		return q, ErrInvalidQuery
+	}
+	q.Type = s[:5]
+	q.Target = strings.TrimSpace(s[5:])
+
+	return q, nil
+}
+
+// Station is the local station configuration used to answer queries.
+type Station struct {
+	Address  *Address
+	Position *Position
+	Symbol   Symbol
+	Comment  string
+	Status   string
+
+	// Heard returns the stations heard direct, used to answer ?APRSD.
+	Heard func() []*Address
+
+	// HeardCount returns the number of packets heard from a station in the
+	// last hour, used to answer ?APRSH.
+	HeardCount func(*Address) int
+
+	// MessageCount and LocalCount return the number of messages and the
+	// number of local stations gated, used to answer ?IGATE?.
+	MessageCount func() int
+	LocalCount   func() int
+
+	// Messages returns the outstanding messages to a station, used to answer
+	// ?APRSM.
+	Messages func(*Address) []Message
+
+	// Objects returns the objects and items originated by the station, used
+	// to answer ?APRSO.
+	Objects func() []PositionReport
+}
+
+// Respond builds the replies for a query packet, it returns no replies for
+// queries that are not addressed at the station or are not supported.
+func (st Station) Respond(p Packet) []Payload {
+	if p.Query == nil {
+		return nil
+	}
+	q := p.Query
+
+	if q.Directed {
+		if p.Message == nil || st.Address == nil {
+			return nil
+		}
+		if a, err := ParseAddress(p.Message.Addressee); err != nil || !st.Address.EqualTo(a) {
+			return nil
+		}
+	} else if q.Footprint != nil && (st.Position == nil || !q.Footprint.Contains(*st.Position)) {
+		return nil
+	}
+
+	switch q.Type {
+	case QueryAPRS, QueryPosition:
+		if st.Position == nil {
+			return nil
+		}
+		pos := *st.Position
+		pos.Symbol = st.Symbol
+		return []Payload{Payload("=" + FormatUncompressedPosition(pos) + st.Comment)}
+
+	case QueryStatus:
+		return []Payload{Payload(">" + st.Status)}
+
+	case QueryIGate:
+		c := Capabilities{CapabilityIGate: ""}
+		if st.MessageCount != nil {
+			c[CapabilityMessageCount] = strconv.Itoa(st.MessageCount())
+		}
+		if st.LocalCount != nil {
+			c[CapabilityLocalCount] = strconv.Itoa(st.LocalCount())
+		}
+		return []Payload{Payload(c.String())}
+	}
+
+	if !q.Directed {
+		return nil
+	}
+
+	// Outstanding messages and objects are answered by sending them again.
+	switch q.Type {
+	case QueryMessages:
+		if st.Messages == nil {
+			return nil
+		}
+		var r []Payload
+		for _, m := range st.Messages(p.Src) {
+			r = append(r, Payload(m.String()))
+		}
+		return r
+
+	case QueryObjects:
+		if st.Objects == nil {
+			return nil
+		}
+		var r []Payload
+		for _, o := range st.Objects() {
+			r = append(r, Payload(o.String()))
+		}
+		return r
+	}
+
+	// Directed queries are answered with a message to the querying station.
+	var text string
+	switch q.Type {
+	case QueryDirect:
+		if st.Heard == nil {
+			return nil
+		}
+		var calls []string
+		for _, a := range st.Heard() {
+			calls = append(calls, a.String())
+		}
+		text = "Directs= " + strings.Join(calls, " ")
+
+	case QueryHeard:
+		if st.HeardCount == nil {
+			return nil
+		}
+		a, err := ParseAddress(q.Target)
+		if err != nil {
+			return nil
+		}
+		text = fmt.Sprintf("%s heard %d packets in the last hour", a, st.HeardCount(a))
+
+	case QueryTrace, QueryPing:
+		path := []string{p.Src.String(), ">", p.Dst.String()}
+		if len(p.Path) > 0 {
+			path = append(path, ",", p.Path.String())
+		}
+		text = strings.Join(path, "")
+
+	default:
+		return nil
+	}
+
+	return []Payload{Payload(Message{Addressee: p.Src.String(), Text: text}.String())}
+}
@@ -0,0 +1,149 @@
+package aprs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidObject signals a corrupted object or item.
+	ErrInvalidObject = errors.New("aprs: invalid object")
+)
+
+// Object is the name and state of an object or item.
+type Object struct {
+	Name   string
+	Killed bool
+	Item   bool // Item rather than object, items carry no time stamp
+}
+
+// ParseObject parses the header of an object report (';'), and returns the
+// object, its time stamp and the remaining data.
+func ParseObject(s string) (Object, time.Time, string, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 11, page 58 (68 in PDF)
+
+	o := Object{}
+
+	if len(s) < 18 || s[0] != ';' || (s[10] != '*' && s[10] != '_') {
+		return o, time.Time{}, "", ErrInvalidObject
+	}
+	o.Name = strings.TrimRight(s[1:10], " ")
+	o.Killed = s[10] == '_'
+	t, err := ParseTime(s[11:18])
+	if err != nil {
+		return o, t, "", err
+	}
+	return o, t, s[18:], nil
+}
+
+// ParseItem parses the header of an item report (')'), and returns the item
+// and the remaining data.
+func ParseItem(s string) (Object, string, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 11, page 59 (69 in PDF)
+
+	o := Object{Item: true}
+
+	if len(s) < 2 || s[0] != ')' {
+		return o, "", ErrInvalidObject
+	}
+	i := strings.IndexAny(s[1:], "!_")
+	if i < 3 || i > 9 {
+		return o, "", ErrInvalidObject
+	}
+	o.Name = s[1 : i+1]
+	o.Killed = s[i+1] == '_'
+	return o, s[i+2:], nil
+}
+
+// PositionReport is a position, object or item report to be encoded.
+type PositionReport struct {
+	Position   Position
+	Object     *Object    // Object or item header, if any
+	Time       *time.Time // Time stamp, if any
+	TimeFormat TimeFormat
+	Messaging  bool // Station is capable of messaging
+	Compressed bool
+	Velocity   *Velocity
+	PHG        *PowerHeightGain
+	Altitude   *Altitude
+	Comment    string
+}
+
+// String formats the report payload. Objects require a time stamp, if Time is
+// nil the current time is used. Object time stamps are zulu, so for objects
+// only the DHM zulu and HMS formats are used. The MDHM format is reserved for
+// positionless weather reports, positions fall back to DHM zulu. Compressed
+// positions carry no course, speed or PHG.
+func (r PositionReport) String() string {
+	var b []string
+
+	switch {
+	case r.Object != nil && r.Object.Item:
+		state := "!"
+		if r.Object.Killed {
+			state = "_"
+		}
+		b = append(b, ")", r.Object.Name, state)
+
+	case r.Object != nil:
+		state := "*"
+		if r.Object.Killed {
+			state = "_"
+		}
+		t := time.Now()
+		if r.Time != nil {
+			t = *r.Time
+		}
+		f := r.TimeFormat
+		if f != TimeHMS {
+			f = TimeDHMZulu
+		}
+		b = append(b, ";", fmt.Sprintf("%-9s", r.Object.Name), state, FormatTime(t, f))
+
+	case r.Time != nil:
+		if r.Messaging {
+			b = append(b, "@")
+		} else {
+			b = append(b, "/")
+		}
+		f := r.TimeFormat
+		if f == TimeMDHM {
+			f = TimeDHMZulu
+		}
+		b = append(b, FormatTime(*r.Time, f))
+
+	case r.Messaging:
+		b = append(b, "=")
+
+	default:
+		b = append(b, "!")
+	}
+
+	if r.Compressed {
+		b = append(b, FormatCompressedPosition(r.Position))
+	} else {
+		b = append(b, FormatUncompressedPosition(r.Position))
+		if r.Velocity != nil {
+			// Course 000 means unknown, north is 360.
+			course := (int(math.Round(r.Velocity.Course))%360 + 360) % 360
+			if course == 0 {
+				course = 360
+			}
+			b = append(b, fmt.Sprintf("%03d/%03d", course, int(math.Round(r.Velocity.Speed.Knots()))))
+		} else if r.PHG != nil {
+			p := r.PHG
+			b = append(b, "PHG", string([]byte{p.PowerCode, p.HeightCode, p.GainCode, p.DirectivityCode}))
+		}
+	}
+	if r.Altitude != nil {
+		b = append(b, fmt.Sprintf("/A=%06d", int(math.Round(r.Altitude.Feet()))))
+	}
+	b = append(b, r.Comment)
+	if !r.Compressed {
+		b = append(b, FormatDAO(r.Position))
+	}
+	return strings.Join(b, "")
+}
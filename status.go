@@ -0,0 +1,180 @@
+package aprs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pd0mz/go-maidenhead"
+)
+
+// Status is a decoded status report.
+type Status struct {
+	Text    string
+	Locator string // Maidenhead locator, if any
+	Symbol  Symbol // Symbol following the Maidenhead locator
+	Beam    BeamHeadingPower
+}
+
+// String formats the status report as an APRS payload, including the leading
+// '>'.
+func (s Status) String() string {
+	var b = []string{">"}
+	if s.Locator != "" {
+		b = append(b, strings.ToUpper(s.Locator), string(s.Symbol[:]))
+		if s.Text != "" {
+			b = append(b, " ")
+		}
+	}
+	b = append(b, s.Text)
+	if s.HasBeam() {
+		b = append(b, "^", string([]byte{s.Beam.HeadingCode, s.Beam.PowerCode}))
+	}
+	return strings.Join(b, "")
+}
+
+// NewLocatorStatus returns a status report with the Maidenhead locator of the
+// position, precision is 4 or 6 characters.
+func NewLocatorStatus(pos Position, precision int, symbol Symbol, text string) (Status, error) {
+	if precision != 4 && precision != 6 {
+		return Status{}, fmt.Errorf("aprs: invalid status locator precision %d", precision)
+	}
+	l, err := pos.Locator(precision)
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Text: text, Locator: l, Symbol: symbol}, nil
+}
+
+// HasBeam returns true if the status report carries a beam heading and
+// power extension.
+func (s Status) HasBeam() bool {
+	return s.Beam.HeadingCode != 0 && s.Beam.PowerCode != 0
+}
+
+// BeamHeadingPower is the ^BP extension at the end of a status report, used
+// by meteor scatter stations to announce where they are beaming.
+type BeamHeadingPower struct {
+	HeadingCode byte
+	PowerCode   byte
+}
+
+func beamValue(b byte) int {
+	switch {
+	case b >= '0' && b <= '9':
+		return int(b - '0')
+	case b >= 'A' && b <= 'Z':
+		return int(b-'A') + 10
+	default:
+		return -1
+	}
+}
+
+// Heading in degrees.
+func (b BeamHeadingPower) Heading() float64 {
+	h := beamValue(b.HeadingCode)
+	if h <= 0 {
+		return 0
+	}
+	return float64(h) * 10.0
+}
+
+// ERP in Watts.
+func (b BeamHeadingPower) ERP() int {
+	p := beamValue(b.PowerCode)
+	if p <= 0 {
+		return 0
+	}
+	return p * p * 10
+}
+
+func isGridLocator(s string) bool {
+	if len(s) != 4 && len(s) != 6 {
+		return false
+	}
+	s = strings.ToUpper(s)
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 0, 1:
+			if s[i] < 'A' || s[i] > 'R' {
+				return false
+			}
+		case 2, 3:
+			if !isDigit(s[i]) {
+				return false
+			}
+		default:
+			if s[i] < 'A' || s[i] > 'X' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseStatus parses a status report (without the leading '>').
+func ParseStatus(s string) (Status, *time.Time, *Position, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 16, page 80 (90 in PDF)
+
+	var (
+		st  Status
+		ts  *time.Time
+		pos *Position
+	)
+
+	switch {
+	case len(s) >= 7 && s[6] == 'z' && isDigits(s[:6]):
+		// Status report with DHM zulu timestamp
+		t, err := ParseTime(s)
+		if err != nil {
+			return st, nil, nil, err
+		}
+		ts = &t
+		s = s[7:]
+
+	case len(s) >= 8 && isGridLocator(s[:6]) && IsValidUncompressedSymTable(s[6]):
+		// Status report with 6 character Maidenhead locator
+		st.Locator = s[:6]
+		st.Symbol[0] = s[6]
+		st.Symbol[1] = s[7]
+		s = strings.TrimPrefix(s[8:], " ")
+
+	case len(s) >= 6 && isGridLocator(s[:4]) && IsValidUncompressedSymTable(s[4]):
+		// Status report with 4 character Maidenhead locator
+		st.Locator = s[:4]
+		st.Symbol[0] = s[4]
+		st.Symbol[1] = s[5]
+		s = strings.TrimPrefix(s[6:], " ")
+	}
+
+	if st.Locator != "" {
+		p, err := maidenhead.ParseLocator(strings.ToUpper(st.Locator))
+		if err != nil {
+			return st, ts, nil, err
+		}
+		pos = &Position{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Symbol:    st.Symbol,
+		}
+	}
+
+	// Meteor scatter beam heading and power
+	if l := len(s); l >= 3 && s[l-3] == '^' && beamValue(s[l-2]) >= 0 && beamValue(s[l-1]) >= 0 {
+		st.Beam.HeadingCode = s[l-2]
+		st.Beam.PowerCode = s[l-1]
+		s = s[:l-3]
+	}
+
+	st.Text = s
+	return st, ts, pos, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
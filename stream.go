@@ -0,0 +1,178 @@
+package aprs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxLineLength is the maximum length of a line on APRS-IS, excluding the
+// line ending.
+const MaxLineLength = 510
+
+var (
+	ErrLineTooLong = errors.New("aprs: line too long")
+)
+
+// LineError is a line that failed to decode. The packet returned with it has
+// all fields that could be parsed, including Raw.
+type LineError struct {
+	Line int    // Line number, starting at 1
+	Raw  string // Raw line, truncated to the maximum length
+	Err  error
+}
+
+func (err *LineError) Error() string {
+	return fmt.Sprintf("aprs: line %d: %v", err.Line, err.Err)
+}
+
+func (err *LineError) Unwrap() error { return err.Err }
+
+// Decoder reads packets in TNC2 format from a stream, one per line. Lines may
+// end in CRLF or LF. Empty lines and comment lines, which start with '#' such
+// as the APRS-IS server messages, are skipped.
+type Decoder struct {
+	// MaxLineLength is the maximum length of a line; longer lines are
+	// truncated and returned with ErrLineTooLong. Defaults to MaxLineLength.
+	MaxLineLength int
+
+	// Latin1 decodes lines that are not valid UTF-8 as ISO-8859-1, as sent by
+	// many older clients. By default, the bytes are kept as received, which
+	// the binary Mic-E and compressed formats rely on.
+	Latin1 bool
+
+	// Mode is the parse mode, in Lenient mode only lines with an invalid
+	// header are returned with a *LineError.
+	Mode ParseMode
+
+	r    *bufio.Reader
+	line int
+}
+
+// NewDecoder returns a decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{MaxLineLength: MaxLineLength, r: br}
+}
+
+// Line returns the line number of the last line read.
+func (d *Decoder) Line() int { return d.line }
+
+// Decode reads the next packet. Lines that fail to decode are returned with
+// a *LineError, after which decoding can continue. Other errors, including
+// io.EOF at the end of the stream, come from the underlying reader.
+func (d *Decoder) Decode() (Packet, error) {
+	for {
+		line, long, err := d.readLine()
+		if line == "" || line[0] == '#' {
+			if err != nil {
+				return Packet{}, err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return Packet{}, err
+		}
+		// The last line may lack a line ending, io.EOF follows on the next call.
+		return d.decode(line, long)
+	}
+}
+
+func (d *Decoder) decode(line string, long bool) (Packet, error) {
+	if d.Latin1 && !utf8.ValidString(line) {
+		line = latin1(line)
+	}
+	if long {
+		return Packet{Raw: line}, &LineError{Line: d.line, Raw: line, Err: ErrLineTooLong}
+	}
+	p, err := ParsePacketMode(line, d.Mode)
+	if err != nil {
+		return p, &LineError{Line: d.line, Raw: line, Err: err}
+	}
+	return p, nil
+}
+
+// readLine reads a line without its line ending, and reports if the line was
+// truncated to the maximum length. The rest of a long line is discarded.
+func (d *Decoder) readLine() (string, bool, error) {
+	limit := d.MaxLineLength
+	if limit <= 0 {
+		limit = MaxLineLength
+	}
+
+	var (
+		b    []byte
+		long bool
+	)
+	for {
+		chunk, err := d.r.ReadSlice('\n')
+		// Leave room for the line ending.
+		if n := limit + 2 - len(b); len(chunk) > n {
+			if n < 0 {
+				n = 0
+			}
+			chunk, long = chunk[:n], true
+		}
+		b = append(b, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && len(b) == 0 {
+			return "", false, err
+		}
+
+		d.line++
+		s := strings.TrimRight(string(b), "\r\n")
+		if len(s) > limit {
+			s, long = s[:limit], true
+		}
+		return s, long, err
+	}
+}
+
+func latin1(s string) string {
+	r := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		r[i] = rune(s[i])
+	}
+	return string(r)
+}
+
+// Encoder writes packets in TNC2 format to a stream, one per line.
+type Encoder struct {
+	// LineEnding terminates every line, defaults to CRLF as used on APRS-IS.
+	LineEnding string
+
+	// MaxLineLength is the maximum length of a line; longer packets are
+	// refused with ErrLineTooLong. Defaults to MaxLineLength.
+	MaxLineLength int
+
+	w io.Writer
+}
+
+// NewEncoder returns an encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{LineEnding: "\r\n", MaxLineLength: MaxLineLength, w: w}
+}
+
+// Encode writes the packet as a line.
+func (e *Encoder) Encode(p Packet) error {
+	if p.Src == nil || p.Dst == nil {
+		return ErrInvalidPacket
+	}
+	line := p.String()
+	if strings.ContainsAny(line, "\r\n") {
+		return ErrInvalidPacket
+	}
+	if e.MaxLineLength > 0 && len(line) > e.MaxLineLength {
+		return ErrLineTooLong
+	}
+	_, err := io.WriteString(e.w, line+e.LineEnding)
+	return err
+}
@@ -1,10 +1,28 @@
 package aprs
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type Symbol [2]byte
 
-func (s Symbol) IsPrimaryTable() bool { return s[0] != '\\' }
+// IsPrimaryTable returns true if the symbol is in the primary table. Overlays
+// apply to the alternate table.
+func (s Symbol) IsPrimaryTable() bool { return s[0] != '\\' && s.Overlay() == 0 }
+
+// Overlay returns the overlay character, or 0 if the symbol has no overlay.
+// The compressed overlays 'a'-'j' are returned as '0'-'9'.
+func (s Symbol) Overlay() byte {
+	switch c := s[0]; {
+	case c >= '0' && c <= '9', c >= 'A' && c <= 'Z':
+		return c
+	case c >= 'a' && c <= 'j':
+		return c - 'a' + '0'
+	default:
+		return 0
+	}
+}
 
 func (s Symbol) get(idx int) (string, error) {
 	var m map[byte]map[int]string
@@ -24,6 +42,11 @@ func (s Symbol) get(idx int) (string, error) {
 }
 
 func (s Symbol) String() string {
+	if o := s.Overlay(); o != 0 {
+		if hr, ok := overlaySymbol[s[1]][o]; ok {
+			return hr
+		}
+	}
 	hr, err := s.get(1)
 	if err != nil {
 		return err.Error()
@@ -31,6 +54,130 @@ func (s Symbol) String() string {
 	return hr
 }
 
+// Code returns the XYZ code of the symbol, the overlay character is appended
+// for overlays.
+func (s Symbol) Code() (string, error) {
+	code, err := s.get(0)
+	if err != nil {
+		return "", err
+	}
+	code = code[:2]
+	if o := s.Overlay(); o != 0 {
+		code += string(o)
+	}
+	return code, nil
+}
+
+// SymbolByCode returns the symbol for an XYZ code, such as "LA" for the
+// ambulance, or "ODS" for the digipeater with overlay 'S'.
+func SymbolByCode(code string) (Symbol, bool) {
+	if len(code) < 2 || len(code) > 3 {
+		return Symbol{}, false
+	}
+	code = strings.ToUpper(code)
+	for _, table := range []byte{'/', '\\'} {
+		m := primarySymbol
+		if table == '\\' {
+			m = alternateSymbol
+		}
+		for c := byte('!'); c <= '~'; c++ {
+			if n, ok := m[c]; !ok || n[0][:2] != code[:2] {
+				continue
+			}
+			if len(code) == 3 {
+				o := code[2]
+				if table == '/' || !(o >= '0' && o <= '9' || o >= 'A' && o <= 'Z') {
+					return Symbol{}, false
+				}
+				return Symbol{o, c}, true
+			}
+			return Symbol{table, c}, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// SymbolByName returns the symbol with the description, ignoring case. Both
+// the symbol tables and the overlay descriptions are searched.
+func SymbolByName(name string) (Symbol, bool) {
+	for _, table := range []byte{'/', '\\'} {
+		m := primarySymbol
+		if table == '\\' {
+			m = alternateSymbol
+		}
+		for c := byte('!'); c <= '~'; c++ {
+			if hr, ok := m[c][1]; ok && strings.EqualFold(hr, name) {
+				return Symbol{table, c}, true
+			}
+		}
+	}
+	for c, n := range overlaySymbol {
+		for o, hr := range n {
+			if strings.EqualFold(hr, name) {
+				return Symbol{o, c}, true
+			}
+		}
+	}
+	return Symbol{}, false
+}
+
+// SymbolFromSSID returns the symbol implied by the SSID of the destination
+// address, for packets without a symbol.
+func SymbolFromSSID(ssid int) (Symbol, bool) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 20, page 94 (104 in PDF)
+	if ssid < 1 || ssid >= len(ssidSymbol) {
+		return Symbol{}, false
+	}
+	return Symbol{'/', ssidSymbol[ssid]}, true
+}
+
+// Symbols on the standard sprite sheets, 16 columns by 6 rows.
+const (
+	SpritePrimary   = iota // Primary table sheet
+	SpriteAlternate        // Alternate table sheet
+	SpriteOverlay          // Overlay characters sheet
+)
+
+// Sprite is a cell of a symbol sprite sheet.
+type Sprite struct {
+	Sheet  int
+	Column int
+	Row    int
+}
+
+// Offset returns the pixel offset of the cell, for symbols of the size.
+func (sp Sprite) Offset(size int) (x, y int) {
+	return sp.Column * size, sp.Row * size
+}
+
+func spriteCell(sheet int, c byte) (Sprite, bool) {
+	if c < '!' || c > '~' {
+		return Sprite{}, false
+	}
+	i := int(c - '!')
+	return Sprite{Sheet: sheet, Column: i % 16, Row: i / 16}, true
+}
+
+// Sprite returns the cell of the symbol on the standard sprite sheets.
+func (s Symbol) Sprite() (Sprite, bool) {
+	if s.IsPrimaryTable() {
+		if s[0] != '/' {
+			return Sprite{}, false
+		}
+		return spriteCell(SpritePrimary, s[1])
+	}
+	return spriteCell(SpriteAlternate, s[1])
+}
+
+// OverlaySprite returns the cell of the overlay character to draw on top of
+// the symbol, if any.
+func (s Symbol) OverlaySprite() (Sprite, bool) {
+	if o := s.Overlay(); o != 0 {
+		return spriteCell(SpriteOverlay, o)
+	}
+	return Sprite{}, false
+}
+
 func (s Symbol) SSID() (string, error) {
 	return s.get(2)
 }
@@ -251,4 +398,75 @@ var (
 		'}':  map[int]string{0: "Q3"},
 		'~':  map[int]string{0: "Q4", 1: "TNC Stream Switch"},
 	}
+
+	// Source: http://www.aprs.org/symbols/symbolsX.txt
+	// Overlay descriptions for alternate table symbols, by code and overlay.
+	overlaySymbol = map[byte]map[byte]string{
+		'!': map[byte]string{'E': "ELT or EPIRB", 'V': "Volcanic Eruption or Lava"},
+		'#': map[byte]string{
+			'1': "WIDE1-1 Digipeater",
+			'A': "Alternate Input Digipeater",
+			'E': "Emergency Powered Digipeater",
+			'I': "I-gate Equipped Digipeater",
+			'L': "WIDEn-N with Path Length Trapping",
+			'P': "PacComm Digipeater",
+			'S': "SSn-N Digipeater",
+			'V': "Viscous Digipeater",
+			'W': "WIDEn-N, SSn-N and Trapping Digipeater",
+			'X': "Experimental Digipeater",
+		},
+		'&': map[byte]string{
+			'2': "TX IGate with 2 Hop Path",
+			'I': "IGate",
+			'P': "PSKmail Node",
+			'R': "Receive Only IGate",
+			'T': "TX IGate with 1 Hop Path",
+			'W': "WIRES-X",
+		},
+		'0': map[byte]string{
+			'A': "Allstar Node",
+			'E': "Echolink Node",
+			'I': "IRLP Repeater",
+			'S': "Staging Area",
+			'V': "Echolink and IRLP",
+			'W': "WIRES",
+		},
+		'^': map[byte]string{
+			'A': "Autonomous Aircraft",
+			'D': "Drone",
+			'E': "Electric Aircraft",
+			'H': "Hovercraft",
+			'J': "Jet",
+			'M': "Missile",
+			'P': "Prop Aircraft",
+			'R': "Remotely Piloted Aircraft",
+			'S': "Solar Powered Aircraft",
+			'V': "Vertical Takeoff Aircraft",
+			'X': "Experimental Aircraft",
+		},
+		's': map[byte]string{
+			'6': "Shipwreck",
+			'B': "Pleasure Boat",
+			'C': "Cargo Ship",
+			'D': "Diving",
+			'E': "Emergency or Medical Transport",
+			'F': "Fishing",
+			'H': "High-speed Craft",
+			'J': "Jet Ski",
+			'L': "Law Enforcement",
+			'M': "Military Ship",
+			'O': "Oil Rig",
+			'P': "Pilot Boat",
+			'Q': "Torpedo",
+			'S': "Search and Rescue",
+			'T': "Tug",
+			'U': "Underwater Ops or Submarine",
+			'W': "Wing-in-Ground Effect",
+			'X': "Passenger Ferry",
+			'Y': "Sailing Ship",
+		},
+	}
+
+	// Primary table symbol codes for destination SSIDs 1-15.
+	ssidSymbol = [16]byte{0, 'a', 'U', 'f', 'b', 'Y', 'X', '\'', 's', '>', '<', 'O', 'j', 'R', 'k', 'v'}
 )
@@ -0,0 +1,57 @@
+package aprs
+
+import "strings"
+
+// ParseThirdParty parses third-party traffic (without the leading '}') into
+// the encapsulated packet.
+func ParseThirdParty(s string) (*Packet, error) {
+	return parseThirdParty(s, Strict)
+}
+
+func parseThirdParty(s string, mode ParseMode) (*Packet, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 17, page 96 (106 in PDF)
+
+	if strings.IndexByte(s, '>') < 0 || strings.IndexByte(s, ':') < 0 {
+		return nil, ErrInvalidPacket
+	}
+	inner, err := ParsePacketMode(s, mode)
+	if err != nil {
+		return nil, err
+	}
+	return &inner, nil
+}
+
+// Unwrap returns the packet as sent by the originating station, by removing
+// all third-party envelopes.
+func (p Packet) Unwrap() Packet {
+	for p.ThirdParty != nil {
+		p = *p.ThirdParty
+	}
+	return p
+}
+
+// Encapsulate wraps the packet in a third-party envelope sent by gate, as
+// used when gating packets from APRS-IS to RF. The path of the encapsulated
+// packet is replaced by TCPIP and the gate.
+func (p Packet) Encapsulate(gate, dst *Address, path Path) Packet {
+	g := *gate
+	g.Repeated = true
+
+	inner := Packet{
+		Src:     p.Src,
+		Dst:     p.Dst,
+		Path:    Path{&Address{Call: "TCPIP"}, &g},
+		Payload: p.Payload,
+	}
+	outer := Packet{
+		Src:     gate,
+		Dst:     dst,
+		Path:    path,
+		Payload: Payload("}" + inner.String()),
+	}
+	outer.Raw = outer.String()
+	if tp, err := ParseThirdParty(string(outer.Payload[1:])); err == nil {
+		outer.ThirdParty = tp
+	}
+	return outer
+}
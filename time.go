@@ -31,3 +31,89 @@ func ParseTime(s string) (time.Time, error) {
 		return time.Time{}, TimeFormatError{s}
 	}
 }
+
+// maxClockSkew is how far a time stamp may be ahead of the receive time.
+const maxClockSkew = 12 * time.Hour
+
+// ResolveTime converts an APRS time stamp to an absolute time, relative to
+// the time the packet was received. Time stamps only carry part of the date,
+// the missing part is taken such that the result is the most recent time
+// before the receive time (allowing for some clock skew), which handles
+// month and year rollover. Local time stamps ('/') are
+// interpreted in loc, or in the location of received if loc is nil. The
+// returned bool reports whether the time stamp was zulu (UTC).
+func ResolveTime(s string, received time.Time, loc *time.Location) (time.Time, bool, error) {
+	t, err := ParseTime(s)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	var zulu = s[6] != '/'
+	if zulu {
+		loc = time.UTC
+	} else if loc == nil {
+		loc = received.Location()
+	}
+	ref := received.In(loc)
+
+	var candidates []time.Time
+	switch {
+	case s[6] == 'z' || s[6] == '/': // DHM, find the month
+		// Look back two months, as the previous month may be too short.
+		for m := -2; m <= 1; m++ {
+			c := time.Date(ref.Year(), ref.Month()+time.Month(m), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if c.Day() == t.Day() {
+				candidates = append(candidates, c)
+			}
+		}
+	case s[6] == 'h': // HMS, find the day
+		for d := -1; d <= 1; d++ {
+			candidates = append(candidates, time.Date(ref.Year(), ref.Month(), ref.Day()+d, t.Hour(), t.Minute(), t.Second(), 0, loc))
+		}
+	default: // MDHM, find the year
+		for y := -1; y <= 1; y++ {
+			c := time.Date(ref.Year()+y, t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if c.Day() == t.Day() {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return time.Time{}, zulu, TimeFormatError{s}
+	}
+
+	// Candidates are sorted, take the most recent one that isn't too far in
+	// the future.
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		if c.Sub(ref) <= maxClockSkew {
+			best = c
+		}
+	}
+	return best, zulu, nil
+}
+
+// TimeFormat is an APRS time stamp format.
+type TimeFormat int
+
+// Time stamp formats.
+const (
+	TimeDHMZulu  TimeFormat = iota // Day/Hours/Minutes zulu, DDHHMMz
+	TimeDHMLocal                   // Day/Hours/Minutes local, DDHHMM/
+	TimeHMS                        // Hours/Minutes/Seconds zulu, HHMMSSh
+	TimeMDHM                       // Month/Day/Hours/Minutes zulu, MMDDHHMM
+)
+
+// FormatTime formats a time stamp, local time stamps use the location of t.
+func FormatTime(t time.Time, f TimeFormat) string {
+	switch f {
+	case TimeDHMLocal:
+		return t.Format("021504") + "/"
+	case TimeHMS:
+		return t.UTC().Format("150405") + "h"
+	case TimeMDHM:
+		return t.UTC().Format("01021504")
+	default:
+		return t.UTC().Format("021504") + "z"
+	}
+}
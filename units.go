@@ -0,0 +1,170 @@
+package aprs
+
+import "fmt"
+
+// UnitSystem selects the units used to format measurements. The String
+// methods of measurements always use Metric, use Format for other systems.
+type UnitSystem int
+
+// Unit systems.
+const (
+	Metric   UnitSystem = iota // Kilometers, km/h, meters, Celsius, hPa
+	Imperial                   // Miles, mph, feet, Fahrenheit, inHg
+	Nautical                   // Nautical miles, knots, feet, Celsius, hPa
+)
+
+// Distance in meters.
+type Distance float64
+
+// Distance units.
+const (
+	Meter        Distance = 1
+	Kilometer    Distance = 1000
+	Foot         Distance = 0.3048
+	Inch         Distance = 0.0254
+	Mile         Distance = 1609.344
+	NauticalMile Distance = 1852
+)
+
+// Meters returns the distance in meters.
+func (d Distance) Meters() float64 { return float64(d) }
+
+// Kilometers returns the distance in kilometers.
+func (d Distance) Kilometers() float64 { return float64(d / Kilometer) }
+
+// Feet returns the distance in feet.
+func (d Distance) Feet() float64 { return float64(d / Foot) }
+
+// Miles returns the distance in statute miles.
+func (d Distance) Miles() float64 { return float64(d / Mile) }
+
+// NauticalMiles returns the distance in nautical miles.
+func (d Distance) NauticalMiles() float64 { return float64(d / NauticalMile) }
+
+// Format the distance in the unit system.
+func (d Distance) Format(u UnitSystem) string {
+	switch u {
+	case Imperial:
+		return fmt.Sprintf("%.1fmi", d.Miles())
+	case Nautical:
+		return fmt.Sprintf("%.1fNM", d.NauticalMiles())
+	default:
+		if d < Kilometer && d > -Kilometer {
+			return fmt.Sprintf("%.fm", d.Meters())
+		}
+		return fmt.Sprintf("%.1fkm", d.Kilometers())
+	}
+}
+
+func (d Distance) String() string { return d.Format(Metric) }
+
+// Altitude in meters.
+type Altitude float64
+
+// Meters returns the altitude in meters.
+func (a Altitude) Meters() float64 { return float64(a) }
+
+// Feet returns the altitude in feet.
+func (a Altitude) Feet() float64 { return Distance(a).Feet() }
+
+// Format the altitude in the unit system.
+func (a Altitude) Format(u UnitSystem) string {
+	switch u {
+	case Imperial, Nautical:
+		return fmt.Sprintf("%.fft", a.Feet())
+	default:
+		return fmt.Sprintf("%.fm", a.Meters())
+	}
+}
+
+func (a Altitude) String() string { return a.Format(Metric) }
+
+// Speed in meters per second.
+type Speed float64
+
+// Speed units.
+const (
+	MeterPerSecond   Speed = 1
+	KilometerPerHour Speed = 1000.0 / 3600
+	MilePerHour      Speed = 1609.344 / 3600
+	Knot             Speed = 1852.0 / 3600
+)
+
+// MetersPerSecond returns the speed in meters per second.
+func (s Speed) MetersPerSecond() float64 { return float64(s) }
+
+// KilometersPerHour returns the speed in kilometers per hour.
+func (s Speed) KilometersPerHour() float64 { return float64(s / KilometerPerHour) }
+
+// MilesPerHour returns the speed in statute miles per hour.
+func (s Speed) MilesPerHour() float64 { return float64(s / MilePerHour) }
+
+// Knots returns the speed in knots.
+func (s Speed) Knots() float64 { return float64(s / Knot) }
+
+// Format the speed in the unit system.
+func (s Speed) Format(u UnitSystem) string {
+	switch u {
+	case Imperial:
+		return fmt.Sprintf("%.fmph", s.MilesPerHour())
+	case Nautical:
+		return fmt.Sprintf("%.fkn", s.Knots())
+	default:
+		return fmt.Sprintf("%.fkm/h", s.KilometersPerHour())
+	}
+}
+
+func (s Speed) String() string { return s.Format(Metric) }
+
+// Temperature in degrees Celsius.
+type Temperature float64
+
+// Fahrenheit returns a temperature from degrees Fahrenheit.
+func Fahrenheit(f float64) Temperature { return Temperature((f - 32) * 5 / 9) }
+
+// Celsius returns the temperature in degrees Celsius.
+func (t Temperature) Celsius() float64 { return float64(t) }
+
+// Fahrenheit returns the temperature in degrees Fahrenheit.
+func (t Temperature) Fahrenheit() float64 { return float64(t)*9/5 + 32 }
+
+// Format the temperature in the unit system.
+func (t Temperature) Format(u UnitSystem) string {
+	switch u {
+	case Imperial:
+		return fmt.Sprintf("%.1f°F", t.Fahrenheit())
+	default:
+		return fmt.Sprintf("%.1f°C", t.Celsius())
+	}
+}
+
+func (t Temperature) String() string { return t.Format(Metric) }
+
+// Pressure in pascal.
+type Pressure float64
+
+// Pressure units.
+const (
+	Pascal        Pressure = 1
+	Hectopascal   Pressure = 100
+	Millibar      Pressure = 100
+	InchOfMercury Pressure = 3386.389
+)
+
+// Hectopascals returns the pressure in hectopascal (millibar).
+func (p Pressure) Hectopascals() float64 { return float64(p / Hectopascal) }
+
+// InchesOfMercury returns the pressure in inches of mercury.
+func (p Pressure) InchesOfMercury() float64 { return float64(p / InchOfMercury) }
+
+// Format the pressure in the unit system.
+func (p Pressure) Format(u UnitSystem) string {
+	switch u {
+	case Imperial:
+		return fmt.Sprintf("%.2finHg", p.InchesOfMercury())
+	default:
+		return fmt.Sprintf("%.1fhPa", p.Hectopascals())
+	}
+}
+
+func (p Pressure) String() string { return p.Format(Metric) }
@@ -0,0 +1,95 @@
+package aprs
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	// ErrInvalidWeather signals a corrupted weather report.
+	ErrInvalidWeather = errors.New("aprs: invalid weather report")
+)
+
+// Weather is a decoded weather report, fields that are not reported are nil.
+type Weather struct {
+	Temperature *Temperature
+	Humidity    *float64 // Percent
+	Pressure    *Pressure
+	RainTotal   *Distance // Long term total
+	RainToday   *Distance // Since midnight
+}
+
+// ParseUltimeter parses an Ultimeter 2000 "$ULTW" data logging mode report,
+// including the leading '$ULTW'.
+func ParseUltimeter(s string) (Weather, Wind, error) {
+	// APRS PROTOCOL REFERENCE 1.0.1 Chapter 12, page 67 (77 in PDF)
+
+	var (
+		wx   Weather
+		wind Wind
+	)
+
+	if len(s) < 5 || s[:5] != "$ULTW" {
+		return wx, wind, ErrInvalidWeather
+	}
+	s = s[5:]
+
+	// Each field is a 4 digit hexadecimal number, or "----" if not available.
+	var f []*float64
+	for i := 0; i+4 <= len(s); i += 4 {
+		if s[i:i+4] == "----" {
+			f = append(f, nil)
+			continue
+		}
+		v, err := strconv.ParseUint(s[i:i+4], 16, 16)
+		if err != nil {
+			return wx, wind, ErrInvalidWeather
+		}
+		n := float64(v)
+		f = append(f, &n)
+	}
+	if len(f) < 9 {
+		return wx, wind, ErrInvalidWeather
+	}
+
+	rain := func(v *float64) *Distance {
+		if v == nil {
+			return nil
+		}
+		n := Distance(*v/100) * Inch
+		return &n
+	}
+
+	// Wind speeds are in 0.1 kph.
+	if f[0] != nil {
+		wind.Gust = Speed(*f[0]/10) * KilometerPerHour
+	}
+	if f[1] != nil {
+		wind.Direction = *f[1] * 360.0 / 256.0
+	}
+	if len(f) >= 13 && f[12] != nil {
+		wind.Speed = Speed(*f[12]/10) * KilometerPerHour
+	}
+
+	if f[2] != nil {
+		// Temperature is a signed number in 0.1 degrees Fahrenheit.
+		t := Fahrenheit(float64(int16(uint16(*f[2]))) / 10)
+		wx.Temperature = &t
+	}
+	wx.RainTotal = rain(f[3])
+	if f[4] != nil {
+		// Pressure is in 0.1 mbar.
+		p := Pressure(*f[4]/10) * Millibar
+		wx.Pressure = &p
+	}
+	if f[8] != nil {
+		// Humidity is in 0.1 percent.
+		h := *f[8] / 10
+		wx.Humidity = &h
+	}
+	if len(f) >= 12 {
+		wx.RainToday = rain(f[11])
+	}
+
+	return wx, wind, nil
+}